@@ -58,7 +58,7 @@ class JSObfuscatorApp {
 
     // 配置变化监听
     const configInputs = document.querySelectorAll(
-      '.config-item input[type="checkbox"]'
+      ".config-item input, .config-item select"
     );
     configInputs.forEach((input) => {
       input.addEventListener("change", () => {
@@ -388,9 +388,27 @@ class JSObfuscatorApp {
       identifierObfuscation: document.getElementById("identifierObfuscation")
         .checked,
       stringEncryption: document.getElementById("stringEncryption").checked,
+      stringArray: document.getElementById("stringArray").checked,
+      splitStrings: document.getElementById("splitStrings").checked,
+      stringCipher: document.getElementById("stringCipher").value,
       controlFlowFlattening: document.getElementById("controlFlowFlattening")
         .checked,
-      // 死代码注入和表达式分解功能已移除
+      deadCodeInjection: document.getElementById("deadCodeInjection").checked,
+      expressionDecomposition: document.getElementById(
+        "expressionDecomposition"
+      ).checked,
+      numbersToExpressions: document.getElementById("numbersToExpressions")
+        .checked,
+      disguiseLiterals: document.getElementById("disguiseLiterals").checked,
+      transformMemberExpressions: document.getElementById(
+        "transformMemberExpressions"
+      ).checked,
+      transformObjectKeys: document.getElementById("transformObjectKeys")
+        .checked,
+      mangleProperties: document.getElementById("mangleProperties").checked,
+      manglePropertiesRegex: document.getElementById("manglePropertiesRegex")
+        .value,
+      selfDefending: document.getElementById("selfDefending").checked,
       compactCode: document.getElementById("compactCode").checked,
       preserveComments: false,
     };
@@ -634,6 +652,8 @@ main();`;
           const element = document.getElementById(key);
           if (element && typeof config[key] === "boolean") {
             element.checked = config[key];
+          } else if (element && typeof config[key] === "string") {
+            element.value = config[key];
           }
         });
       }
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"js-obfuscator/obfuscator"
+)
+
+// 与 obfuscator.Config 字段对应的命令行选项
+//
+// 选项名取自字段的 json 标签，配置项新增字段后无需修改命令行工具。
+type configFlag struct {
+	kind   reflect.Kind
+	values []string
+}
+
+func (f *configFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(f.values, ",")
+}
+
+func (f *configFlag) Set(value string) error {
+	if err := checkFlagValue(f.kind, value); err != nil {
+		return err
+	}
+	// 列表选项每次指定一个值，可以重复指定；值原样保留，正则表达式中的逗号不会被拆开
+	if f.kind == reflect.Slice {
+		if value != "" {
+			f.values = append(f.values, value)
+		}
+		return nil
+	}
+	f.values = []string{value}
+	return nil
+}
+
+func (f *configFlag) IsBoolFlag() bool {
+	return f.kind == reflect.Bool
+}
+
+func checkFlagValue(kind reflect.Kind, value string) error {
+	var err error
+	switch kind {
+	case reflect.Bool:
+		_, err = strconv.ParseBool(value)
+	case reflect.Int, reflect.Int64:
+		_, err = strconv.ParseInt(value, 10, 64)
+	case reflect.Float64:
+		_, err = strconv.ParseFloat(value, 64)
+	}
+	if err != nil {
+		return errors.New("无效的取值: " + value)
+	}
+	return nil
+}
+
+// 为配置结构体的每个字段注册同名选项
+func registerConfigFlags(fs *flag.FlagSet) map[string]*configFlag {
+	flags := make(map[string]*configFlag)
+	t := reflect.TypeOf(obfuscator.Config{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := jsonName(field)
+		if name == "" {
+			continue
+		}
+		// 指针字段（如比例配置项）未指定时保持 nil
+		typ := field.Type
+		if typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		kind := typ.Kind()
+		switch kind {
+		case reflect.Bool, reflect.String, reflect.Int, reflect.Int64, reflect.Float64:
+		case reflect.Slice:
+			if typ.Elem().Kind() != reflect.String {
+				continue
+			}
+		default:
+			continue
+		}
+		f := &configFlag{kind: kind}
+		flags[name] = f
+		usage := "配置项 " + name
+		if kind == reflect.Slice {
+			usage += "，每次指定一个值，可重复指定"
+		}
+		fs.Var(f, name, usage)
+	}
+	return flags
+}
+
+// 将命令行中出现过的选项写入配置
+func applyConfigFlags(config *obfuscator.Config, flags map[string]*configFlag) error {
+	v := reflect.ValueOf(config).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f, ok := flags[jsonName(t.Field(i))]
+		if !ok || len(f.values) == 0 {
+			continue
+		}
+		field := v.Field(i)
+		if field.Kind() == reflect.Pointer {
+			field.Set(reflect.New(field.Type().Elem()))
+			field = field.Elem()
+		}
+		value := f.values[len(f.values)-1]
+		switch f.kind {
+		case reflect.Bool:
+			b, _ := strconv.ParseBool(value)
+			field.SetBool(b)
+		case reflect.String:
+			field.SetString(value)
+		case reflect.Int, reflect.Int64:
+			n, _ := strconv.ParseInt(value, 10, 64)
+			field.SetInt(n)
+		case reflect.Float64:
+			x, _ := strconv.ParseFloat(value, 64)
+			field.SetFloat(x)
+		case reflect.Slice:
+			field.Set(reflect.ValueOf(append([]string(nil), f.values...)).Convert(field.Type()))
+		default:
+			return errors.New("不支持的配置项: " + t.Field(i).Name)
+		}
+	}
+	return nil
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
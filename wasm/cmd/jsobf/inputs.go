@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// 可重复指定的 glob 模式列表
+type patternList []string
+
+func (p *patternList) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *patternList) Set(value string) error {
+	for _, pattern := range strings.Split(value, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return errors.New("无效的 glob 模式: " + pattern)
+		}
+		*p = append(*p, pattern)
+	}
+	return nil
+}
+
+// 判断相对路径是否匹配任一模式
+//
+// 不含 / 的模式只匹配文件名，含 / 的模式匹配完整的相对路径，** 匹配任意层目录。
+func (p patternList) match(rel string) bool {
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range p {
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+				return true
+			}
+			continue
+		}
+		if matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// 根据命令行参数展开待处理的文件列表
+func collectJobs(args []string, output string, includes, excludes patternList) ([]job, error) {
+	if len(args) == 0 {
+		args = []string{"-"}
+	}
+	if len(args) == 1 && args[0] == "-" {
+		return []job{{output: output}}, nil
+	}
+
+	// 目录或多个输入时，-o 表示输出目录
+	multiple := len(args) > 1
+	for _, arg := range args {
+		if arg == "-" {
+			return nil, errors.New("标准输入不能与其他输入同时使用")
+		}
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			multiple = true
+		}
+	}
+	if multiple && output == "" {
+		return nil, errors.New("目录或多个输入需要使用 -o 指定输出目录")
+	}
+
+	// 输出目录位于输入目录内时，跳过其中已有的产物
+	outputAbs, _ := filepath.Abs(output)
+
+	var jobs []job
+	for _, arg := range args {
+		info, _ := os.Stat(arg)
+		if !info.IsDir() {
+			out := output
+			if multiple {
+				out = filepath.Join(output, filepath.Base(arg))
+			}
+			jobs = append(jobs, job{input: arg, output: out})
+			continue
+		}
+
+		root := arg
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil || rel == "." {
+				return err
+			}
+			if d.IsDir() {
+				if abs, _ := filepath.Abs(p); abs == outputAbs || excludes.match(rel) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() || !includes.match(rel) || excludes.match(rel) {
+				return nil
+			}
+			jobs = append(jobs, job{input: p, output: filepath.Join(output, rel)})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 不同目录下的同名文件会写到同一个输出路径，互相覆盖
+	inputs := make(map[string]string, len(jobs))
+	for _, j := range jobs {
+		key := filepath.Clean(j.output)
+		if previous, ok := inputs[key]; ok {
+			return nil, fmt.Errorf("%s 与 %s 的输出路径相同: %s", previous, j.input, j.output)
+		}
+		inputs[key] = j.input
+	}
+	return jobs, nil
+}
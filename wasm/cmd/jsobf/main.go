@@ -0,0 +1,259 @@
+// jsobf 是混淆引擎的命令行工具，适用于没有浏览器的 CI 环境。
+//
+// 用法：
+//
+//	jsobf [选项] [文件或目录 ...]
+//
+// 不指定输入（或输入为 -）时从标准输入读取。单个输入默认写到标准输出，
+// 目录或多个输入需要用 -o 指定输出目录，输出保持原有的相对路径。
+// 混淆配置可以通过 -config 指定 JSON 文件（格式与前端配置相同），
+// 也可以用与 JSON 字段同名的选项逐项设置，选项优先于配置文件。
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"js-obfuscator/obfuscator"
+)
+
+// 退出码
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("jsobf", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	configFile := fs.String("config", "", "JSON 配置文件路径")
+	output := fs.String("o", "", "输出文件（单个输入）或输出目录（目录或多个输入）")
+	validate := fs.Bool("validate", true, "校验输入与混淆结果的语法，失败时以非零状态退出")
+	listTransforms := fs.Bool("list-transforms", false, "列出内置变换及其配置项后退出")
+	renameMap := fs.Bool("rename-map", false, "在输出文件旁写出标识符改名映射（<输出文件>.renames.json）")
+	propertyCache := fs.String("property-cache", "", "属性名映射文件，构建前读取（不存在时忽略），构建后写回")
+	var includes, excludes patternList
+	fs.Var(&includes, "include", "目录遍历时包含的 glob 模式，可重复指定（默认 *.js）")
+	fs.Var(&excludes, "exclude", "目录遍历时排除的 glob 模式，可重复指定")
+	configFlags := registerConfigFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "用法: jsobf [选项] [文件或目录 ...]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if *listTransforms {
+		printTransforms(stdout, obfuscator.DefaultRegistry())
+		return exitOK
+	}
+
+	// 配置文件在前，命令行选项覆盖其中的同名字段
+	var config obfuscator.Config
+	if *configFile != "" {
+		data, err := os.ReadFile(*configFile)
+		if err != nil {
+			fmt.Fprintln(stderr, "读取配置文件失败:", err)
+			return exitUsage
+		}
+		if err := json.Unmarshal(data, &config); err != nil {
+			fmt.Fprintln(stderr, "配置解析失败:", err)
+			return exitUsage
+		}
+	}
+	if err := applyConfigFlags(&config, configFlags); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	if *propertyCache != "" {
+		propertyMap, err := readPropertyCache(*propertyCache)
+		if err != nil {
+			fmt.Fprintln(stderr, "读取属性名映射失败:", err)
+			return exitUsage
+		}
+		if propertyMap != nil {
+			config.PropertyMap = propertyMap
+		}
+	}
+
+	if len(includes) == 0 {
+		includes = patternList{"*.js"}
+	}
+	jobs, err := collectJobs(fs.Args(), *output, includes, excludes)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+
+	p := &processor{
+		config:    config,
+		validate:  *validate,
+		renameMap: *renameMap,
+		stdin:     stdin,
+		stdout:    stdout,
+		stderr:    stderr,
+	}
+	status := exitOK
+	for _, job := range jobs {
+		if err := p.process(job); err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", job.displayName(), err)
+			status = exitFailure
+		}
+	}
+	if *propertyCache != "" && p.config.PropertyMap != nil {
+		if err := writePropertyCache(*propertyCache, p.config.PropertyMap); err != nil {
+			fmt.Fprintln(stderr, "写出属性名映射失败:", err)
+			status = exitFailure
+		}
+	}
+	return status
+}
+
+// 读取属性名映射文件，文件不存在时返回 nil
+func readPropertyCache(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var propertyMap map[string]string
+	if err := json.Unmarshal(data, &propertyMap); err != nil {
+		return nil, err
+	}
+	return propertyMap, nil
+}
+
+func writePropertyCache(path string, propertyMap map[string]string) error {
+	data, err := json.MarshalIndent(propertyMap, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// 按执行顺序输出变换列表
+func printTransforms(w io.Writer, registry *obfuscator.Registry) {
+	for _, t := range registry.Transforms() {
+		fmt.Fprintf(w, "%s\t%s\n", t.Name(), t.Description())
+		for _, option := range t.Options() {
+			fmt.Fprintf(w, "  -%s (%s)\t%s\n", option.Name, option.Type, option.Description)
+		}
+	}
+}
+
+// 单个待处理的文件
+type job struct {
+	input  string // 为空表示标准输入
+	output string // 为空表示标准输出
+}
+
+func (j job) displayName() string {
+	if j.input == "" {
+		return "<stdin>"
+	}
+	return j.input
+}
+
+type processor struct {
+	config    obfuscator.Config
+	validate  bool
+	renameMap bool
+	stdin     io.Reader
+	stdout    io.Writer
+	stderr    io.Writer
+}
+
+func (p *processor) process(j job) error {
+	var src []byte
+	var err error
+	if j.input == "" {
+		src, err = io.ReadAll(p.stdin)
+	} else {
+		src, err = os.ReadFile(j.input)
+	}
+	if err != nil {
+		return err
+	}
+
+	if p.validate {
+		if ok, problems := obfuscator.Validate(string(src)); !ok {
+			return errors.New("输入校验失败: " + strings.Join(problems, "; "))
+		}
+	}
+
+	config := p.config
+	if config.SourceMap && config.SourceFileName == "" && j.input != "" {
+		config.SourceFileName = filepath.Base(j.input)
+	}
+	result, err := obfuscator.Obfuscate(string(src), config)
+	if err != nil {
+		return errors.New("混淆失败: " + err.Error())
+	}
+	// 之后的文件沿用已经分配的属性名
+	if result.PropertyMap != nil {
+		p.config.PropertyMap = result.PropertyMap
+	}
+
+	if p.validate && strings.TrimSpace(result.Code) != "" {
+		if ok, problems := obfuscator.Validate(result.Code); !ok {
+			return errors.New("输出校验失败: " + strings.Join(problems, "; "))
+		}
+	}
+
+	if j.output == "" {
+		if result.SourceMap != "" {
+			fmt.Fprintf(p.stderr, "%s: 输出到标准输出时不写出 source map\n", j.displayName())
+		}
+		if p.renameMap {
+			fmt.Fprintf(p.stderr, "%s: 输出到标准输出时不写出改名映射\n", j.displayName())
+		}
+		_, err = io.WriteString(p.stdout, result.Code)
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(j.output), 0o755); err != nil {
+		return err
+	}
+
+	// source map 写到输出文件旁边，并在代码末尾引用
+	code := result.Code
+	if result.SourceMap != "" {
+		mapFile := j.output + ".map"
+		if err := os.WriteFile(mapFile, []byte(result.SourceMap), 0o644); err != nil {
+			return err
+		}
+		code += "//# sourceMappingURL=" + filepath.Base(mapFile) + "\n"
+	}
+	if p.renameMap {
+		entries := result.RenameMap
+		if entries == nil {
+			entries = []obfuscator.RenameEntry{}
+		}
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(j.output+".renames.json", data, 0o644); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(j.output, []byte(code), 0o644)
+}
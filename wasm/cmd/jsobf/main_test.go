@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"js-obfuscator/obfuscator"
+)
+
+// 在临时目录中写出文件，files 的键是相对路径
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// 列出目录下所有文件的相对路径
+func listTree(t *testing.T, root string) []string {
+	t.Helper()
+	var names []string
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(root, p)
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func runCLI(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// 目录输入递归处理，按 include/exclude 过滤，输出保持相对路径
+func TestRunDirectory(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	out := filepath.Join(dir, "dist")
+	writeTree(t, src, map[string]string{
+		"main.js":            "var a = 1;",
+		"lib/util.js":        "var b = 2;",
+		"lib/deep/more.js":   "var c = 3;",
+		"lib/util.test.js":   "var d = 4;",
+		"vendor/jquery.js":   "var e = 5;",
+		"scripts/site.jsx":   "var f = 6;",
+		"lib/deep/notes.txt": "text",
+	})
+
+	code, _, stderr := runCLI(t, "", "-exclude", "*.test.js", "-exclude", "vendor/**", "-o", out, src)
+	if code != exitOK {
+		t.Fatalf("退出码 %d: %s", code, stderr)
+	}
+	got := strings.Join(listTree(t, out), " ")
+	want := "lib/deep/more.js lib/util.js main.js"
+	if got != want {
+		t.Errorf("输出文件 %q，期望 %q", got, want)
+	}
+
+	// 指定 include 后不再使用默认的 *.js
+	out = filepath.Join(dir, "jsx")
+	code, _, stderr = runCLI(t, "", "-include", "**/*.jsx", "-o", out, src)
+	if code != exitOK {
+		t.Fatalf("退出码 %d: %s", code, stderr)
+	}
+	if got := strings.Join(listTree(t, out), " "); got != "scripts/site.jsx" {
+		t.Errorf("输出文件 %q，期望 scripts/site.jsx", got)
+	}
+}
+
+func TestPatternListMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		rel     string
+		want    bool
+	}{
+		{"*.js", "a.js", true},
+		{"*.js", "lib/deep/a.js", true},
+		{"*.js", "a.json", false},
+		{"lib/*.js", "lib/a.js", true},
+		{"lib/*.js", "lib/deep/a.js", false},
+		{"lib/**/*.js", "lib/a.js", true},
+		{"lib/**/*.js", "lib/deep/er/a.js", true},
+		{"**/vendor/**", "x/vendor/y/a.js", true},
+		{"vendor/**", "src/vendor/a.js", false},
+	}
+	for _, tt := range tests {
+		if got := (patternList{tt.pattern}).match(tt.rel); got != tt.want {
+			t.Errorf("%q 匹配 %q = %v，期望 %v", tt.pattern, tt.rel, got, tt.want)
+		}
+	}
+}
+
+// 多个输入写到同一个输出路径时报错，不写出任何文件
+func TestRunOutputCollision(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"a/index.js": "var a = 1;",
+		"b/index.js": "var b = 2;",
+	})
+	out := filepath.Join(dir, "dist")
+	code, _, stderr := runCLI(t, "", "-o", out, filepath.Join(dir, "a", "index.js"), filepath.Join(dir, "b", "index.js"))
+	if code != exitUsage {
+		t.Fatalf("退出码 %d，期望 %d: %s", code, exitUsage, stderr)
+	}
+	if !strings.Contains(stderr, "输出路径相同") {
+		t.Errorf("错误信息没有说明冲突: %s", stderr)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("冲突时仍然写出了输出目录")
+	}
+
+	// 目录输入保持相对路径，不会冲突
+	code, _, stderr = runCLI(t, "", "-o", out, dir)
+	if code != exitOK {
+		t.Fatalf("退出码 %d: %s", code, stderr)
+	}
+	if got := strings.Join(listTree(t, out), " "); got != "a/index.js b/index.js" {
+		t.Errorf("输出文件 %q", got)
+	}
+}
+
+// 命令行选项覆盖配置文件中的同名字段，其余字段沿用配置文件
+func TestRunConfigFileAndFlags(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "obfuscator.json")
+	writeTree(t, dir, map[string]string{
+		"obfuscator.json": `{"identifierObfuscation": true, "compactCode": true, "reservedNames": ["keepMe"], "seed": 7}`,
+	})
+	src := "function outer() { var keepMe = 1; var renamed = 2; return keepMe + renamed; }\nconsole.log(outer());\n"
+
+	code, fromFile, stderr := runCLI(t, src, "-config", config)
+	if code != exitOK {
+		t.Fatalf("退出码 %d: %s", code, stderr)
+	}
+	if strings.Contains(fromFile, "renamed") || !strings.Contains(fromFile, "keepMe") {
+		t.Errorf("没有按配置文件改名:\n%s", fromFile)
+	}
+	if strings.Count(strings.TrimSpace(fromFile), "\n") != 0 {
+		t.Errorf("配置文件中的 compactCode 没有生效:\n%s", fromFile)
+	}
+
+	code, fromFlags, stderr := runCLI(t, src, "-config", config, "-compactCode=false", "-reservedNames", "renamed")
+	if code != exitOK {
+		t.Fatalf("退出码 %d: %s", code, stderr)
+	}
+	if !strings.Contains(fromFlags, "renamed") || strings.Contains(fromFlags, "keepMe") {
+		t.Errorf("reservedNames 选项没有覆盖配置文件:\n%s", fromFlags)
+	}
+	if strings.Count(strings.TrimSpace(fromFlags), "\n") == 0 {
+		t.Errorf("-compactCode=false 没有覆盖配置文件:\n%s", fromFlags)
+	}
+
+	writeTree(t, dir, map[string]string{"broken.json": `{"compactCode": `})
+	if code, _, _ := runCLI(t, src, "-config", filepath.Join(dir, "broken.json")); code != exitUsage {
+		t.Errorf("配置文件无法解析时退出码 %d，期望 %d", code, exitUsage)
+	}
+}
+
+// 输入未通过语法校验时以非零状态退出，关闭校验后交给混淆引擎报错
+func TestRunValidationFailure(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"good.js": "var ok = 1;",
+		"bad.js":  "var broken = ;",
+	})
+	out := filepath.Join(dir, "dist")
+	code, _, stderr := runCLI(t, "", "-o", out, filepath.Join(dir, "good.js"), filepath.Join(dir, "bad.js"))
+	if code != exitFailure {
+		t.Fatalf("退出码 %d，期望 %d", code, exitFailure)
+	}
+	if !strings.Contains(stderr, "bad.js") || !strings.Contains(stderr, "输入校验失败") {
+		t.Errorf("错误信息没有指出失败的文件: %s", stderr)
+	}
+	// 其他文件照常处理
+	if got := strings.Join(listTree(t, out), " "); got != "good.js" {
+		t.Errorf("输出文件 %q，期望 good.js", got)
+	}
+
+	code, stdout, _ := runCLI(t, "let x = 1;")
+	if code != exitFailure || stdout != "" {
+		t.Errorf("标准输入校验失败时退出码 %d、输出 %q", code, stdout)
+	}
+	code, _, stderr = runCLI(t, "let x = 1;", "-validate=false")
+	if code != exitFailure || strings.Contains(stderr, "输入校验失败") {
+		t.Errorf("关闭校验后退出码 %d: %s", code, stderr)
+	}
+}
+
+// 比例配置项只在指定时设置，未指定时保持 nil 以使用默认值
+func TestThresholdFlags(t *testing.T) {
+	fs := flag.NewFlagSet("jsobf", flag.ContinueOnError)
+	flags := registerConfigFlags(fs)
+	if err := fs.Parse([]string{"-controlFlowFlatteningThreshold", "0"}); err != nil {
+		t.Fatal(err)
+	}
+	var config obfuscator.Config
+	if err := applyConfigFlags(&config, flags); err != nil {
+		t.Fatal(err)
+	}
+	if config.ControlFlowFlatteningThreshold == nil || *config.ControlFlowFlatteningThreshold != 0 {
+		t.Errorf("controlFlowFlatteningThreshold = %v，期望 0", config.ControlFlowFlatteningThreshold)
+	}
+
+	fs = flag.NewFlagSet("jsobf", flag.ContinueOnError)
+	flags = registerConfigFlags(fs)
+	if err := fs.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	config = obfuscator.Config{}
+	if err := applyConfigFlags(&config, flags); err != nil {
+		t.Fatal(err)
+	}
+	if config.ControlFlowFlatteningThreshold != nil {
+		t.Errorf("未指定时 controlFlowFlatteningThreshold = %v，期望 nil", *config.ControlFlowFlatteningThreshold)
+	}
+	if config.DeadCodeInjectionThreshold != nil {
+		t.Errorf("未指定时 deadCodeInjectionThreshold = %v，期望 nil", *config.DeadCodeInjectionThreshold)
+	}
+	if config.StringArrayThreshold != nil {
+		t.Errorf("未指定时 stringArrayThreshold = %v，期望 nil", *config.StringArrayThreshold)
+	}
+}
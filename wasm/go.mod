@@ -4,9 +4,7 @@ go 1.21
 
 require (
 	github.com/robertkrimen/otto v0.3.0
+	gopkg.in/sourcemap.v1 v1.0.5
 )
 
-require (
-	github.com/sourcegraph/go-diff v0.7.0 // indirect
-	gopkg.in/sourcemap.v1 v1.0.5 // indirect
-)
\ No newline at end of file
+require golang.org/x/text v0.4.0 // indirect
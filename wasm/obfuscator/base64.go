@@ -0,0 +1,167 @@
+package obfuscator
+
+import (
+	"strings"
+
+	"github.com/robertkrimen/otto/ast"
+)
+
+// 标准 Base64 字母表
+const standardBase64Alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// 运行时 Base64 解码函数模板：字节解码器，以及在其上还原 UTF-16 字符串的解码器
+//
+// 字母表以外的字符（包括填充的 =）会被跳过，输出不带填充。
+const (
+	base64BytesTemplate = `
+function __BYTES__(__S__) {
+	var __CHARS__ = __ALPHABET__;
+	var __RESULT__ = [], __BUFFER__ = 0, __BITS__ = 0;
+	for (var __I__ = 0; __I__ < __S__.length; __I__++) {
+		var __C__ = __CHARS__.indexOf(__S__.charAt(__I__));
+		if (__C__ < 0) {
+			continue;
+		}
+		__BUFFER__ = (__BUFFER__ << 6 | __C__) & 65535;
+		__BITS__ += 6;
+		if (__BITS__ >= 8) {
+			__BITS__ -= 8;
+			__RESULT__.push(__BUFFER__ >> __BITS__ & 255);
+		}
+	}
+	return __RESULT__;
+}`
+	base64StringTemplate = `
+function __STRING__(__S__) {
+	var __B__ = __BYTES__(__S__), __RESULT__ = '';
+	for (var __I__ = 0; __I__ + 1 < __B__.length; __I__ += 2) {
+		__RESULT__ += String.fromCharCode(__B__[__I__] << 8 | __B__[__I__ + 1]);
+	}
+	return __RESULT__;
+}`
+)
+
+// 一次混淆中共享的 Base64 运行时
+//
+// 所有调用点共用同一对解码函数，只在实际用到时插入；
+// 启用 shuffleBase64Alphabet 时每次构建使用随机打乱的字母表。
+type base64Runtime struct {
+	ctx        *Context
+	alphabet   string
+	bytesName  string // 字节解码函数名
+	stringName string // 字符串解码函数名
+
+	// 已经插入的解码函数
+	bytesEmitted  bool
+	stringEmitted bool
+}
+
+func newBase64Runtime(ctx *Context) *base64Runtime {
+	alphabet := standardBase64Alphabet
+	if ctx.Config.ShuffleBase64Alphabet {
+		shuffled := []byte(alphabet)
+		ctx.Rand.Shuffle(len(shuffled), func(i, j int) {
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		})
+		alphabet = string(shuffled)
+	}
+	return &base64Runtime{ctx: ctx, alphabet: alphabet}
+}
+
+// 字节解码函数名，首次调用时分配
+func (b *base64Runtime) bytesDecoder() string {
+	if b.bytesName == "" {
+		b.bytesName = b.ctx.newName()
+	}
+	return b.bytesName
+}
+
+// 字符串解码函数名，首次调用时分配
+func (b *base64Runtime) stringDecoder() string {
+	if b.stringName == "" {
+		b.stringName = b.ctx.newName()
+		b.bytesDecoder()
+	}
+	return b.stringName
+}
+
+// 按当前字母表编码
+func (b *base64Runtime) encode(data []byte) string {
+	return base64Encode(data, b.alphabet)
+}
+
+// 将字符串编码为字符串解码函数的调用
+func (b *base64Runtime) encodeString(units []uint16) ast.Expression {
+	return &ast.CallExpression{
+		Callee:       &ast.Identifier{Name: b.stringDecoder()},
+		ArgumentList: []ast.Expression{&ast.StringLiteral{Value: b.encode(unitBytes(units))}},
+	}
+}
+
+// 用到但尚未插入的解码函数
+func (b *base64Runtime) runtime() []ast.Statement {
+	var stmts []ast.Statement
+	if b.bytesName != "" && !b.bytesEmitted {
+		b.bytesEmitted = true
+		template := base64BytesTemplate
+		// 打开自我保护时每个字符的值与检查结果异或，代码被改动后解码出的字节全部错误
+		if b.ctx.Config.SelfDefending {
+			template = strings.Replace(template, "| __C__)", "| __C__ ^ "+b.ctx.selfDefendingCheck().result+")", 1)
+		}
+		decoder := b.ctx.parseRuntime(template, map[string]string{
+			"__BYTES__":    b.bytesName,
+			"__ALPHABET__": quoteString(b.alphabet),
+			"__S__":        b.ctx.newName(),
+			"__CHARS__":    b.ctx.newName(),
+			"__RESULT__":   b.ctx.newName(),
+			"__BUFFER__":   b.ctx.newName(),
+			"__BITS__":     b.ctx.newName(),
+			"__I__":        b.ctx.newName(),
+			"__C__":        b.ctx.newName(),
+		})
+		// 字节解码函数同时参与自我保护的校验
+		if b.ctx.Config.SelfDefending {
+			b.ctx.selfDefendingCheck().include(decoder[0].(*ast.FunctionStatement).Function)
+		}
+		stmts = append(stmts, decoder...)
+	}
+	if b.stringName != "" && !b.stringEmitted {
+		b.stringEmitted = true
+		stmts = append(stmts, b.ctx.parseRuntime(base64StringTemplate, map[string]string{
+			"__STRING__": b.stringName,
+			"__BYTES__":  b.bytesName,
+			"__S__":      b.ctx.newName(),
+			"__B__":      b.ctx.newName(),
+			"__RESULT__": b.ctx.newName(),
+			"__I__":      b.ctx.newName(),
+		})...)
+	}
+	return stmts
+}
+
+// 每个 UTF-16 码元按高位在前拆成两个字节
+func unitBytes(units []uint16) []byte {
+	data := make([]byte, 0, len(units)*2)
+	for _, unit := range units {
+		data = append(data, byte(unit>>8), byte(unit))
+	}
+	return data
+}
+
+// Base64 编码，不输出填充
+func base64Encode(data []byte, alphabet string) string {
+	result := make([]byte, 0, (len(data)*4+2)/3)
+	for i := 0; i < len(data); i += 3 {
+		var chunk [3]byte
+		n := copy(chunk[:], data[i:])
+		value := int(chunk[0])<<16 | int(chunk[1])<<8 | int(chunk[2])
+		result = append(result, alphabet[value>>18&63], alphabet[value>>12&63])
+		if n > 1 {
+			result = append(result, alphabet[value>>6&63])
+		}
+		if n > 2 {
+			result = append(result, alphabet[value&63])
+		}
+	}
+	return string(result)
+}
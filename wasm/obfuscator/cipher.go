@@ -0,0 +1,179 @@
+package obfuscator
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/robertkrimen/otto/ast"
+)
+
+// 字符串加密算法
+const (
+	CipherNone = ""    // 只做编码，不使用密钥
+	CipherRC4  = "rc4" // RC4 流密码
+	CipherXOR  = "xor" // 滚动异或
+)
+
+// 运行时解密函数模板
+//
+// 密文是 Base64 编码的字节序列（解码函数与 Base64 策略共用），每两个字节还原为一个 UTF-16 码元，
+// 因此任何字符串（包括单独的代理项）都能原样还原。
+// 解密结果缓存在函数自身的属性上，同一个字符串只解密一次。
+const cipherDecoderTemplate = `
+function __DECODE__(__DATA__, __KEY__) {
+	var __CACHE__ = __DECODE__.__CACHE_PROP__ || (__DECODE__.__CACHE_PROP__ = {});
+	var __ID__ = '$' + __KEY__ + ':' + __DATA__;
+	if (__CACHE__.hasOwnProperty(__ID__)) {
+		return __CACHE__[__ID__];
+	}
+	var __BYTES__ = __BASE64__(__DATA__);
+	__CIPHER__
+	var __OUT__ = '';
+	for (var __I__ = 0; __I__ + 1 < __BYTES__.length; __I__ += 2) {
+		__OUT__ += String.fromCharCode(__BYTES__[__I__] << 8 | __BYTES__[__I__ + 1]);
+	}
+	return __CACHE__[__ID__] = __OUT__;
+}
+`
+
+// 各算法的解密步骤，原地改写 __BYTES__
+var cipherTemplates = map[string]string{
+	CipherRC4: `
+	var __STATE__ = [], __A__ = 0, __B__ = 0, __T__, __N__;
+	for (__N__ = 0; __N__ < 256; __N__++) {
+		__STATE__[__N__] = __N__;
+	}
+	for (__N__ = 0; __N__ < 256; __N__++) {
+		__B__ = (__B__ + __STATE__[__N__] + __KEY__.charCodeAt(__N__ % __KEY__.length)) % 256;
+		__T__ = __STATE__[__N__]; __STATE__[__N__] = __STATE__[__B__]; __STATE__[__B__] = __T__;
+	}
+	__B__ = 0;
+	for (__N__ = 0; __N__ < __BYTES__.length; __N__++) {
+		__A__ = (__A__ + 1) % 256;
+		__B__ = (__B__ + __STATE__[__A__]) % 256;
+		__T__ = __STATE__[__A__]; __STATE__[__A__] = __STATE__[__B__]; __STATE__[__B__] = __T__;
+		__BYTES__[__N__] ^= __STATE__[(__STATE__[__A__] + __STATE__[__B__]) % 256];
+	}`,
+	CipherXOR: `
+	var __PREV__ = __KEY__.length & 255, __T__;
+	for (var __N__ = 0; __N__ < __BYTES__.length; __N__++) {
+		__T__ = __BYTES__[__N__];
+		__BYTES__[__N__] = __T__ ^ __KEY__.charCodeAt(__N__ % __KEY__.length) ^ __PREV__;
+		__PREV__ = __T__;
+	}`,
+}
+
+// 模板中的局部名称占位符
+var cipherPlaceholders = []string{
+	"__DATA__", "__KEY__", "__CACHE__", "__ID__", "__BYTES__", "__OUT__", "__I__",
+	"__STATE__", "__A__", "__B__", "__T__", "__N__", "__PREV__",
+}
+
+// 使用密钥加密字符串，每个字符串有独立的随机密钥
+type keyedEncryptor struct {
+	ctx     *Context
+	cipher  string
+	base64  *base64Runtime
+	decoder string // 解密函数名，首次使用时生成运行时代码
+	emitted bool   // 解密函数已经插入
+}
+
+func newKeyedEncryptor(ctx *Context, base64 *base64Runtime) (*keyedEncryptor, error) {
+	cipher := ctx.Config.StringCipher
+	if _, ok := cipherTemplates[cipher]; !ok {
+		return nil, errors.New("未知的字符串加密算法: " + cipher)
+	}
+	return &keyedEncryptor{ctx: ctx, cipher: cipher, base64: base64}, nil
+}
+
+// 解密函数名，首次调用时分配
+func (e *keyedEncryptor) decoderName() string {
+	if e.decoder == "" {
+		e.decoder = e.ctx.newName()
+		e.base64.bytesDecoder()
+	}
+	return e.decoder
+}
+
+// 用新的随机密钥加密字符串，返回 Base64 编码的密文和密钥
+func (e *keyedEncryptor) seal(units []uint16) (payload, key string) {
+	key = randomKey(e.ctx, 4+e.ctx.Rand.Intn(5))
+	data := unitBytes(units)
+	switch e.cipher {
+	case CipherRC4:
+		rc4Crypt(data, key)
+	case CipherXOR:
+		rollingXOR(data, key)
+	}
+	return e.base64.encode(data), key
+}
+
+// 将字符串替换为解密函数调用
+func (e *keyedEncryptor) encrypt(units []uint16) ast.Expression {
+	decoder := e.decoderName()
+	payload, key := e.seal(units)
+	return &ast.CallExpression{
+		Callee: &ast.Identifier{Name: decoder},
+		ArgumentList: []ast.Expression{
+			&ast.StringLiteral{Value: payload},
+			&ast.StringLiteral{Value: key},
+		},
+	}
+}
+
+// 生成解密函数，没有字符串被加密或已经插入时返回 nil
+func (e *keyedEncryptor) runtime() []ast.Statement {
+	if e.decoder == "" || e.emitted {
+		return nil
+	}
+	e.emitted = true
+	names := map[string]string{
+		"__DECODE__":     e.decoder,
+		"__BASE64__":     e.base64.bytesDecoder(),
+		"__CACHE_PROP__": e.ctx.newName(),
+	}
+	for _, placeholder := range cipherPlaceholders {
+		names[placeholder] = e.ctx.newName()
+	}
+	src := strings.Replace(cipherDecoderTemplate, "__CIPHER__", cipherTemplates[e.cipher], 1)
+	return e.ctx.parseRuntime(src, names)
+}
+
+// 随机密钥，只使用字母和数字
+func randomKey(ctx *Context, length int) string {
+	const chars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	key := make([]byte, length)
+	for i := range key {
+		key[i] = chars[ctx.Rand.Intn(len(chars))]
+	}
+	return string(key)
+}
+
+// RC4 加密（加解密相同）
+func rc4Crypt(data []byte, key string) {
+	var state [256]byte
+	for i := range state {
+		state[i] = byte(i)
+	}
+	j := 0
+	for i := 0; i < 256; i++ {
+		j = (j + int(state[i]) + int(key[i%len(key)])) % 256
+		state[i], state[j] = state[j], state[i]
+	}
+	a, b := 0, 0
+	for n := range data {
+		a = (a + 1) % 256
+		b = (b + int(state[a])) % 256
+		state[a], state[b] = state[b], state[a]
+		data[n] ^= state[(int(state[a])+int(state[b]))%256]
+	}
+}
+
+// 滚动异或：每个字节与密钥字节以及前一个密文字节异或
+func rollingXOR(data []byte, key string) {
+	prev := byte(len(key))
+	for n := range data {
+		data[n] ^= key[n%len(key)] ^ prev
+		prev = data[n]
+	}
+}
@@ -0,0 +1,408 @@
+package obfuscator
+
+import (
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/token"
+)
+
+func newControlFlowTransform() Transform {
+	return &builtinTransform{
+		name:        "controlFlow",
+		description: "将函数体拆成基本块，由打乱顺序的 switch 状态机调度",
+		options: []Option{
+			{Name: "controlFlowFlattening", Type: "boolean", Description: "启用控制流平坦化"},
+			{Name: "controlFlowFlatteningThreshold", Type: "number", Description: "平坦化的函数比例（0~1，未设置时为 1，0 表示不平坦化）"},
+		},
+		enabled: func(cfg *Config) bool { return cfg.ControlFlowFlattening },
+		apply: func(ctx *Context) error {
+			flattenControlFlow(ctx)
+			return nil
+		},
+	}
+}
+
+// 控制流平坦化
+//
+// 每个函数体（以及顶层代码）按比例选中后拆成基本块，每个块是 switch 中的一个 case，
+// 由状态变量决定下一个执行的块，case 的顺序和状态值都是随机的：
+//
+//	var state = 3;
+//	label: while (true) {
+//		switch (state) {
+//		case 7: ...; state = 1; break;
+//		case 3: state = test ? 7 : 5; break;
+//		case 5: break label;
+//		}
+//	}
+//
+// if、while、do-while 和 for 拆成条件跳转；switch、try、for-in、with 整体放进一个块，
+// 其中跳出到外层循环的 break/continue 改写为设置状态后继续调度。
+// 函数声明提升到状态机之前，保证在整个作用域内可见。
+func flattenControlFlow(ctx *Context) {
+	threshold := thresholdValue(ctx.Config.ControlFlowFlatteningThreshold, 1)
+
+	// 先收集再改写，内层函数体在外层之前处理
+	var bodies []*[]ast.Statement
+	walker := &astWalker{
+		leave: func(node ast.Node) ast.Node {
+			if fn, ok := node.(*ast.FunctionLiteral); ok {
+				if body, ok := fn.Body.(*ast.BlockStatement); ok {
+					bodies = append(bodies, &body.List)
+				}
+			}
+			return node
+		},
+	}
+	walker.walk(ctx.Program)
+	bodies = append(bodies, &ctx.Program.Body)
+
+	for _, body := range bodies {
+		if ctx.Rand.Float64() < threshold {
+			*body = flattenBody(ctx, *body)
+		}
+	}
+}
+
+// 跳转目标：循环或带标签的语句
+type flowTarget struct {
+	labels     []string
+	loop       bool
+	breakTo    int
+	continueTo int
+}
+
+// 单个函数体的平坦化状态
+type flattener struct {
+	ctx     *Context
+	state   string // 状态变量名
+	label   string // 调度循环的标签
+	cases   []*ast.CaseStatement
+	blocks  map[int]*ast.CaseStatement
+	targets []flowTarget
+}
+
+// 平坦化一个语句列表，语句太少时原样返回
+func flattenBody(ctx *Context, list []ast.Statement) []ast.Statement {
+	// 指令序言保持在最前面，函数声明紧随其后
+	directives, rest := splitDirectives(list)
+	hoisted := append([]ast.Statement(nil), directives...)
+	var body []ast.Statement
+	for _, stmt := range rest {
+		if _, ok := stmt.(*ast.FunctionStatement); ok {
+			hoisted = append(hoisted, stmt)
+		} else {
+			body = append(body, stmt)
+		}
+	}
+	if len(body) < 2 {
+		return list
+	}
+
+	f := &flattener{
+		ctx:    ctx,
+		state:  ctx.newName(),
+		label:  ctx.newName(),
+		blocks: make(map[int]*ast.CaseStatement),
+	}
+	exit := f.block(&ast.BranchStatement{Token: token.BREAK, Label: &ast.Identifier{Name: f.label}})
+	entry := f.compileList(body, exit)
+	ctx.Rand.Shuffle(len(f.cases), func(i, j int) {
+		f.cases[i], f.cases[j] = f.cases[j], f.cases[i]
+	})
+
+	return append(hoisted,
+		&ast.VariableStatement{List: []ast.Expression{
+			&ast.VariableExpression{Name: f.state, Initializer: numberLiteral(entry)},
+		}},
+		&ast.LabelledStatement{
+			Label: &ast.Identifier{Name: f.label},
+			Statement: &ast.WhileStatement{
+				Test: &ast.BooleanLiteral{Literal: "true", Value: true},
+				Body: &ast.BlockStatement{List: []ast.Statement{
+					&ast.SwitchStatement{
+						Discriminant: &ast.Identifier{Name: f.state},
+						Default:      -1,
+						Body:         f.cases,
+					},
+				}},
+			},
+		},
+	)
+}
+
+// 新建一个基本块，返回它的状态值
+func (f *flattener) block(stmts ...ast.Statement) int {
+	id := f.ctx.Rand.Intn(100000)
+	for f.blocks[id] != nil {
+		id = f.ctx.Rand.Intn(100000)
+	}
+	c := &ast.CaseStatement{Test: numberLiteral(id), Consequent: stmts}
+	f.blocks[id] = c
+	f.cases = append(f.cases, c)
+	return id
+}
+
+// 填充预先占位的块
+func (f *flattener) fill(id int, stmts []ast.Statement) {
+	f.blocks[id].Consequent = stmts
+}
+
+// 设置状态
+func (f *flattener) setState(state ast.Expression) ast.Statement {
+	return &ast.ExpressionStatement{Expression: &ast.AssignExpression{
+		Operator: token.ASSIGN,
+		Left:     &ast.Identifier{Name: f.state},
+		Right:    state,
+	}}
+}
+
+// 设置状态并跳出 switch，进入下一轮调度
+func (f *flattener) jump(state ast.Expression) []ast.Statement {
+	return []ast.Statement{f.setState(state), &ast.BranchStatement{Token: token.BREAK}}
+}
+
+// 语句后接跳转
+func (f *flattener) then(stmt ast.Statement, next int) []ast.Statement {
+	return append([]ast.Statement{stmt}, f.jump(numberLiteral(next))...)
+}
+
+// 条件跳转
+func (f *flattener) branch(test ast.Expression, consequent, alternate int) []ast.Statement {
+	return f.jump(&ast.ConditionalExpression{
+		Test:       test,
+		Consequent: numberLiteral(consequent),
+		Alternate:  numberLiteral(alternate),
+	})
+}
+
+// 编译语句列表，执行完后转到 next，返回入口状态
+func (f *flattener) compileList(list []ast.Statement, next int) int {
+	for i := len(list) - 1; i >= 0; i-- {
+		next = f.compile(list[i], next, nil)
+	}
+	return next
+}
+
+// 编译单条语句，执行完后转到 next，返回入口状态；labels 是语句上的标签
+func (f *flattener) compile(stmt ast.Statement, next int, labels []string) int {
+	if stmt == nil {
+		return next
+	}
+	// 块中的函数声明在各引擎中的行为不一致，包含它们的语句整体保留
+	if containsFunctionStatement(stmt) {
+		return f.opaque(stmt, next, labels)
+	}
+
+	switch s := stmt.(type) {
+	case *ast.EmptyStatement:
+		return next
+	case *ast.BlockStatement:
+		if len(labels) > 0 {
+			f.push(flowTarget{labels: labels, breakTo: next})
+			defer f.pop()
+		}
+		return f.compileList(s.List, next)
+	case *ast.LabelledStatement:
+		return f.compile(s.Statement, next, append(labels, s.Label.Name))
+	case *ast.ReturnStatement, *ast.ThrowStatement:
+		return f.block(s)
+	case *ast.BranchStatement:
+		if target, ok := f.resolve(s); ok {
+			return target
+		}
+		return f.opaque(s, next, labels)
+	case *ast.IfStatement:
+		if len(labels) > 0 {
+			f.push(flowTarget{labels: labels, breakTo: next})
+			defer f.pop()
+		}
+		consequent := f.compile(s.Consequent, next, nil)
+		alternate := f.compile(s.Alternate, next, nil)
+		return f.block(f.branch(s.Test, consequent, alternate)...)
+	case *ast.WhileStatement:
+		// 先占住条件块的状态值，循环体才能跳回来
+		header := f.block()
+		f.push(flowTarget{labels: labels, loop: true, breakTo: next, continueTo: header})
+		body := f.compile(s.Body, header, nil)
+		f.pop()
+		f.fill(header, f.branch(s.Test, body, next))
+		return header
+	case *ast.DoWhileStatement:
+		test := f.block()
+		f.push(flowTarget{labels: labels, loop: true, breakTo: next, continueTo: test})
+		body := f.compile(s.Body, test, nil)
+		f.pop()
+		f.fill(test, f.branch(s.Test, body, next))
+		return body
+	case *ast.ForStatement:
+		header := f.block()
+		update := header
+		if s.Update != nil {
+			update = f.block(f.then(&ast.ExpressionStatement{Expression: s.Update}, header)...)
+		}
+		f.push(flowTarget{labels: labels, loop: true, breakTo: next, continueTo: update})
+		body := f.compile(s.Body, update, nil)
+		f.pop()
+		if s.Test != nil {
+			f.fill(header, f.branch(s.Test, body, next))
+		} else {
+			f.fill(header, f.jump(numberLiteral(body)))
+		}
+		if init := forInitializerStatement(s.Initializer); init != nil {
+			return f.block(f.then(init, header)...)
+		}
+		return header
+	case *ast.ExpressionStatement, *ast.VariableStatement, *ast.DebuggerStatement:
+		return f.block(f.then(s, next)...)
+	}
+	return f.opaque(stmt, next, labels)
+}
+
+// 整体保留的语句：放进一个块，其中跳出该语句的 break/continue 改写为状态跳转
+func (f *flattener) opaque(stmt ast.Statement, next int, labels []string) int {
+	stmt = f.rewriteJumps(stmt, labels)
+	for i := len(labels) - 1; i >= 0; i-- {
+		stmt = &ast.LabelledStatement{Label: &ast.Identifier{Name: labels[i]}, Statement: stmt}
+	}
+	return f.block(f.then(stmt, next)...)
+}
+
+// 改写语句中目标在语句之外的 break/continue，labels 是语句自身的标签
+func (f *flattener) rewriteJumps(stmt ast.Statement, labels []string) ast.Statement {
+	// 语句内部的循环、switch 和标签
+	type inner struct {
+		label     string
+		loop      bool
+		breakable bool
+	}
+	var stack []inner
+	for _, label := range labels {
+		stack = append(stack, inner{label: label})
+	}
+	walker := &astWalker{
+		enter: func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.FunctionLiteral:
+				return false
+			case *ast.WhileStatement, *ast.DoWhileStatement, *ast.ForStatement, *ast.ForInStatement:
+				stack = append(stack, inner{loop: true, breakable: true})
+			case *ast.SwitchStatement:
+				stack = append(stack, inner{breakable: true})
+			case *ast.LabelledStatement:
+				stack = append(stack, inner{label: n.Label.Name})
+			}
+			return true
+		},
+		leave: func(node ast.Node) ast.Node {
+			switch n := node.(type) {
+			case *ast.WhileStatement, *ast.DoWhileStatement, *ast.ForStatement, *ast.ForInStatement,
+				*ast.SwitchStatement, *ast.LabelledStatement:
+				stack = stack[:len(stack)-1]
+			case *ast.BranchStatement:
+				for i := len(stack) - 1; i >= 0; i-- {
+					s := stack[i]
+					if n.Label != nil && s.label == n.Label.Name ||
+						n.Label == nil && (s.loop || s.breakable && n.Token == token.BREAK) {
+						return node
+					}
+				}
+				if target, ok := f.resolve(n); ok {
+					return &ast.BlockStatement{List: []ast.Statement{
+						f.setState(numberLiteral(target)),
+						&ast.BranchStatement{Token: token.CONTINUE, Label: &ast.Identifier{Name: f.label}},
+					}}
+				}
+			}
+			return node
+		},
+	}
+	return walker.walk(stmt).(ast.Statement)
+}
+
+// 查找 break/continue 对应的状态值
+func (f *flattener) resolve(branch *ast.BranchStatement) (int, bool) {
+	for i := len(f.targets) - 1; i >= 0; i-- {
+		target := f.targets[i]
+		if branch.Label != nil {
+			for _, label := range target.labels {
+				if label == branch.Label.Name {
+					if branch.Token == token.CONTINUE {
+						return target.continueTo, target.loop
+					}
+					return target.breakTo, true
+				}
+			}
+			continue
+		}
+		if target.loop {
+			if branch.Token == token.CONTINUE {
+				return target.continueTo, true
+			}
+			return target.breakTo, true
+		}
+	}
+	return 0, false
+}
+
+func (f *flattener) push(target flowTarget) {
+	f.targets = append(f.targets, target)
+}
+
+func (f *flattener) pop() {
+	f.targets = f.targets[:len(f.targets)-1]
+}
+
+// for 语句的初始化部分转为独立语句
+func forInitializerStatement(init ast.Expression) ast.Statement {
+	if init == nil {
+		return nil
+	}
+	if seq, ok := init.(*ast.SequenceExpression); ok {
+		// for (;;) 的初始化部分是空的逗号表达式
+		if len(seq.Sequence) == 0 {
+			return nil
+		}
+		if _, ok := seq.Sequence[0].(*ast.VariableExpression); ok {
+			return &ast.VariableStatement{List: seq.Sequence}
+		}
+	}
+	return &ast.ExpressionStatement{Expression: init}
+}
+
+// 语句中（不含嵌套函数）是否有函数声明
+func containsFunctionStatement(stmt ast.Statement) bool {
+	found := false
+	walker := &astWalker{
+		enter: func(node ast.Node) bool {
+			switch node.(type) {
+			case *ast.FunctionStatement:
+				found = true
+				return false
+			case *ast.FunctionLiteral:
+				return false
+			}
+			return !found
+		},
+	}
+	walker.walk(stmt)
+	return found
+}
+
+// 拆分出语句列表开头的指令序言
+func splitDirectives(list []ast.Statement) ([]ast.Statement, []ast.Statement) {
+	for i, stmt := range list {
+		es, ok := stmt.(*ast.ExpressionStatement)
+		if !ok {
+			return list[:i], list[i:]
+		}
+		if _, ok := es.Expression.(*ast.StringLiteral); !ok {
+			return list[:i], list[i:]
+		}
+	}
+	return list, nil
+}
+
+// 整数字面量节点
+func numberLiteral(value int) *ast.NumberLiteral {
+	return &ast.NumberLiteral{Literal: intToString(value), Value: int64(value)}
+}
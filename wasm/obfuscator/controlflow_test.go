@@ -0,0 +1,119 @@
+package obfuscator
+
+import (
+	"strings"
+	"testing"
+)
+
+// 控制流平坦化前后的运行结果相同
+func TestControlFlowFlatteningEquivalence(t *testing.T) {
+	node := requireNode(t)
+	src := strings.Join([]string{
+		"var log = [];",
+		// 函数声明提升：调用出现在声明之前
+		"function hoisted() {",
+		"  log.push(inner(1));",
+		"  var v = 2;",
+		"  function inner(x) { return x + (v || 0); }",
+		"  log.push(inner(1));",
+		"}",
+		"hoisted();",
+		// 循环中的 break / continue
+		"function loops() {",
+		"  var out = [];",
+		"  for (var i = 0; i < 10; i++) {",
+		"    if (i === 2) continue;",
+		"    if (i === 7) break;",
+		"    out.push(i);",
+		"  }",
+		"  var j = 0;",
+		"  while (true) { j++; if (j % 2) continue; if (j > 6) break; out.push('w' + j); }",
+		"  do { j--; if (j === 5) continue; out.push('d' + j); } while (j > 3);",
+		"  for (var k in { a: 1, b: 2, c: 3 }) { if (k === 'b') continue; out.push(k); }",
+		"  log.push(out.join());",
+		"}",
+		"loops();",
+		// 标签与跨层 break / continue
+		"function labels() {",
+		"  var out = [];",
+		"  outer: for (var i = 0; i < 4; i++) {",
+		"    inner: for (var j = 0; j < 4; j++) {",
+		"      if (j === i) continue outer;",
+		"      if (i === 3) break outer;",
+		"      out.push(i + '' + j);",
+		"    }",
+		"  }",
+		"  block: { out.push('in'); if (out.length) break block; out.push('never'); }",
+		"  log.push(out.join());",
+		"}",
+		"labels();",
+		// try / catch / finally 中的 return 与 break
+		"function tries(n) {",
+		"  var out = [];",
+		"  for (var i = 0; i < n; i++) {",
+		"    try {",
+		"      if (i === 1) continue;",
+		"      if (i === 3) throw new Error('e' + i);",
+		"      if (i === 4) break;",
+		"      out.push('t' + i);",
+		"    } catch (e) {",
+		"      out.push(e.message);",
+		"    } finally {",
+		"      out.push('f' + i);",
+		"    }",
+		"  }",
+		"  try { return out.join(); } finally { log.push('finally'); }",
+		"}",
+		"log.push(tries(6));",
+		// switch 穿透与 default 在中间
+		"function sw(x) {",
+		"  var out = [];",
+		"  switch (x) {",
+		"    case 1: out.push('one');",
+		"    case 2: out.push('two'); break;",
+		"    default: out.push('default');",
+		"    case 3: out.push('three');",
+		"    case 4: out.push('four'); break;",
+		"    case 5: return 'five';",
+		"  }",
+		"  return out.join();",
+		"}",
+		"for (var n = 0; n <= 6; n++) log.push(sw(n));",
+		"console.log(log.join('|'));",
+	}, "\n")
+	configs := map[string]Config{
+		"flatten":  {ControlFlowFlattening: true, ControlFlowFlatteningThreshold: Threshold(1)},
+		"combined": {ControlFlowFlattening: true, ControlFlowFlatteningThreshold: Threshold(1), IdentifierObfuscation: true, ExpressionDecomposition: true},
+	}
+	checkEquivalent(t, node, src, configs, 5)
+}
+
+// 比例未设置时平坦化全部函数，0 时一个也不平坦化，1 时全部平坦化
+func TestControlFlowThreshold(t *testing.T) {
+	src := strings.Join([]string{
+		"function a(x) { var y = x + 1; if (y > 2) { y = y * 2; } return y; }",
+		"function b(x) { var s = 0; for (var i = 0; i < x; i++) { s += i; } return s; }",
+		"function c(x) { var t = x; t = t - 1; return t * 3; }",
+		"console.log(a(1), b(4), c(5));",
+	}, "\n")
+	for seed := int64(1); seed <= 3; seed++ {
+		obfuscate := func(threshold *float64) string {
+			result, err := Obfuscate(src, Config{ControlFlowFlattening: true, ControlFlowFlatteningThreshold: threshold, CompactCode: true, Seed: seed})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return result.Code
+		}
+		unset, all, none := obfuscate(nil), obfuscate(Threshold(1)), obfuscate(Threshold(0))
+		if unset != all {
+			t.Errorf("seed %d: 未设置比例的结果与比例 1 不同:\n%s\n%s", seed, unset, all)
+		}
+		// 每个函数一个状态机；顶层的函数声明提升后只剩一条语句，不平坦化
+		if n := strings.Count(all, "switch("); n != 3 {
+			t.Errorf("seed %d: 比例 1 时有 %d 个状态机:\n%s", seed, n, all)
+		}
+		if strings.Contains(none, "switch(") {
+			t.Errorf("seed %d: 比例 0 时仍然平坦化:\n%s", seed, none)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+package obfuscator
+
+import (
+	"strings"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/token"
+)
+
+func newDeadCodeTransform() Transform {
+	return &builtinTransform{
+		name:        "deadCode",
+		description: "在函数中插入由不透明谓词保护、永远不会执行的代码",
+		options: []Option{
+			{Name: "deadCodeInjection", Type: "boolean", Description: "启用死代码注入"},
+			{Name: "deadCodeInjectionThreshold", Type: "number", Description: "注入死代码的语句比例（0~1，未设置时为 0.4，0 表示不注入）"},
+		},
+		enabled: func(cfg *Config) bool { return cfg.DeadCodeInjection },
+		apply: func(ctx *Context) error {
+			injectDeadCode(ctx)
+			return nil
+		},
+	}
+}
+
+// 克隆来源函数的最大长度（紧凑输出的字符数），避免输出过度膨胀
+const maxDeadCodeSource = 2000
+
+// 未指定比例时注入死代码的语句比例
+const defaultDeadCodeThreshold = 0.4
+
+// 不透明谓词模板，对任意非负整数 __V__ 恒为真；__EQ__、__NE__ 互换后恒为假
+var opaquePredicates = []string{
+	`__V__ * (__V__ + 1) % 2 __EQ__ 0`,
+	`(__V__ * __V__ * __V__ - __V__) % 3 __EQ__ 0`,
+	`7 * __V__ * __V__ - 1 __NE__ __V__ * __V__`,
+	`(__V__ * __V__ + 1) % 7 __NE__ 0`,
+}
+
+// 可克隆的函数
+type deadCodeSource struct {
+	text   string // 函数表达式的源码，每次注入重新解析得到新的节点
+	strict bool
+}
+
+// 死代码注入
+//
+// 函数中的语句按比例改写为 if (P) { 原语句 } else { 死代码 }（或谓词取反、分支互换），
+// P 是基于 arguments.length 的不透明谓词，静态分析难以确定其值。
+// 死代码是同一文件中某个函数体的克隆，其中声明的名称和标签全部换成新名称，
+// 数字和运算符随机改变，不会影响宿主函数的作用域。
+// 克隆体中含有 return，只注入到函数中；严格模式的函数只使用严格模式的克隆来源。
+// 谓词依赖 arguments 是函数真正的参数对象，改动了 arguments 的函数不注入。
+func injectDeadCode(ctx *Context) {
+	threshold := thresholdValue(ctx.Config.DeadCodeInjectionThreshold, defaultDeadCodeThreshold)
+
+	// 注入前先记下所有函数的原始代码
+	var sources []deadCodeSource
+	strict := []bool{isStrict(ctx.Program.Body)}
+	walker := &astWalker{
+		enter: func(node ast.Node) bool {
+			if fn, ok := node.(*ast.FunctionLiteral); ok {
+				strict = append(strict, functionStrict(fn, strict[len(strict)-1]))
+				if body, ok := fn.Body.(*ast.BlockStatement); ok && len(body.List) > 0 {
+					text := generateCode(&ast.Program{Body: []ast.Statement{
+						&ast.ExpressionStatement{Expression: fn},
+					}}, ctx.Source, true, nil)
+					if len(text) <= maxDeadCodeSource {
+						sources = append(sources, deadCodeSource{text: text, strict: strict[len(strict)-1]})
+					}
+				}
+			}
+			return true
+		},
+		leave: func(node ast.Node) ast.Node {
+			if _, ok := node.(*ast.FunctionLiteral); ok {
+				strict = strict[:len(strict)-1]
+			}
+			return node
+		},
+	}
+	walker.walk(ctx.Program)
+	if len(sources) == 0 {
+		return
+	}
+
+	// 每层函数的 arguments 是否可以用于谓词，与 strict 同步
+	intact := []bool{false}
+	inject := func(list []ast.Statement) {
+		if len(strict) < 2 || !intact[len(intact)-1] {
+			return
+		}
+		var candidates []deadCodeSource
+		for _, source := range sources {
+			if source.strict || !strict[len(strict)-1] {
+				candidates = append(candidates, source)
+			}
+		}
+		if len(candidates) == 0 {
+			return
+		}
+		for i, stmt := range list {
+			if !deadCodeHost(stmt) || ctx.Rand.Float64() >= threshold {
+				continue
+			}
+			source := candidates[ctx.Rand.Intn(len(candidates))]
+			list[i] = guardWithDeadCode(ctx, stmt, cloneDeadCode(ctx, source.text))
+		}
+	}
+	walker = &astWalker{
+		enter: func(node ast.Node) bool {
+			if fn, ok := node.(*ast.FunctionLiteral); ok {
+				strict = append(strict, functionStrict(fn, strict[len(strict)-1]))
+				intact = append(intact, argumentsIntact(fn))
+			}
+			return true
+		},
+		leave: func(node ast.Node) ast.Node {
+			switch n := node.(type) {
+			case *ast.FunctionLiteral:
+				strict = strict[:len(strict)-1]
+				intact = intact[:len(intact)-1]
+			case *ast.BlockStatement:
+				inject(n.List)
+			case *ast.CaseStatement:
+				inject(n.Consequent)
+			}
+			return node
+		},
+	}
+	walker.walk(ctx.Program)
+}
+
+// 可以包进 if 分支的语句；指令序言形式的字符串语句不处理
+func deadCodeHost(stmt ast.Statement) bool {
+	switch s := stmt.(type) {
+	case *ast.ExpressionStatement:
+		_, directive := s.Expression.(*ast.StringLiteral)
+		return !directive
+	case *ast.VariableStatement, *ast.ReturnStatement:
+		return true
+	}
+	return false
+}
+
+// 函数中的 arguments 是否一定是未被改动的参数对象
+//
+// 以下情况返回 false：参数、变量、内层函数或 catch 参数名为 arguments，
+// 对 arguments 或 arguments.length 赋值、自增自减或作为 for-in 的目标，
+// 以及 with 语句和直接 eval，它们可能让 arguments 指向别的值。
+// 内层函数有自己的 arguments，不检查其函数体。
+func argumentsIntact(fn *ast.FunctionLiteral) bool {
+	if fn.ParameterList != nil {
+		for _, param := range fn.ParameterList.List {
+			if param.Name == "arguments" {
+				return false
+			}
+		}
+	}
+	intact := true
+	walker := &astWalker{
+		enter: func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.FunctionLiteral:
+				if n != fn {
+					if n.Name != nil && n.Name.Name == "arguments" {
+						intact = false
+					}
+					return false
+				}
+			case *ast.VariableExpression:
+				if n.Name == "arguments" {
+					intact = false
+				}
+			case *ast.CatchStatement:
+				if n.Parameter.Name == "arguments" {
+					intact = false
+				}
+			case *ast.AssignExpression:
+				if changesArguments(n.Left) {
+					intact = false
+				}
+			case *ast.UnaryExpression:
+				if (n.Operator == token.INCREMENT || n.Operator == token.DECREMENT) && changesArguments(n.Operand) {
+					intact = false
+				}
+			case *ast.ForInStatement:
+				if changesArguments(n.Into) {
+					intact = false
+				}
+			case *ast.WithStatement:
+				intact = false
+			case *ast.CallExpression:
+				if callee, ok := n.Callee.(*ast.Identifier); ok && callee.Name == "eval" {
+					intact = false
+				}
+			}
+			return intact
+		},
+	}
+	walker.walk(fn)
+	return intact
+}
+
+// 写入目标是否会改变 arguments 本身或它的 length
+func changesArguments(target ast.Expression) bool {
+	switch t := target.(type) {
+	case *ast.Identifier:
+		return t.Name == "arguments"
+	case *ast.DotExpression:
+		object, ok := t.Left.(*ast.Identifier)
+		return ok && object.Name == "arguments" && t.Identifier.Name == "length"
+	case *ast.BracketExpression:
+		object, ok := t.Left.(*ast.Identifier)
+		if !ok || object.Name != "arguments" {
+			return false
+		}
+		_, index := t.Member.(*ast.NumberLiteral)
+		return !index
+	}
+	return false
+}
+
+// 用不透明谓词把语句和死代码放进 if 的两个分支
+func guardWithDeadCode(ctx *Context, stmt ast.Statement, dead []ast.Statement) ast.Statement {
+	live := &ast.BlockStatement{List: []ast.Statement{stmt}}
+	deadBlock := &ast.BlockStatement{List: dead}
+	if ctx.Rand.Intn(2) == 0 {
+		return &ast.IfStatement{Test: opaquePredicate(ctx, true), Consequent: live, Alternate: deadBlock}
+	}
+	return &ast.IfStatement{Test: opaquePredicate(ctx, false), Consequent: deadBlock, Alternate: live}
+}
+
+// 生成取值固定的不透明谓词
+func opaquePredicate(ctx *Context, value bool) ast.Expression {
+	eq, ne := "===", "!=="
+	if !value {
+		eq, ne = ne, eq
+	}
+	template := opaquePredicates[ctx.Rand.Intn(len(opaquePredicates))]
+	stmts := ctx.parseRuntime(template, map[string]string{
+		"__V__":  "arguments.length",
+		"__EQ__": eq,
+		"__NE__": ne,
+	})
+	return stmts[0].(*ast.ExpressionStatement).Expression
+}
+
+// 重新解析克隆来源，改名并随机变异后返回函数体
+func cloneDeadCode(ctx *Context, text string) []ast.Statement {
+	fn := ctx.parseRuntime(text, nil)[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+
+	// 克隆体中声明的所有名称和标签都换成新名称，var 提升后不会遮蔽宿主函数中的变量
+	renames := make(map[string]string)
+	rename := func(name string) {
+		if renames[name] == "" {
+			renames[name] = ctx.newName()
+		}
+	}
+	collector := &astWalker{
+		enter: func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.VariableExpression:
+				rename(n.Name)
+			case *ast.FunctionLiteral:
+				if n.Name != nil {
+					rename(n.Name.Name)
+				}
+				if n.ParameterList != nil {
+					for _, param := range n.ParameterList.List {
+						rename(param.Name)
+					}
+				}
+			case *ast.CatchStatement:
+				rename(n.Parameter.Name)
+			case *ast.LabelledStatement:
+				rename(n.Label.Name)
+			}
+			return true
+		},
+	}
+	collector.walk(fn)
+
+	renameIdentifier := func(id *ast.Identifier) {
+		if id != nil && renames[id.Name] != "" {
+			id.Name = renames[id.Name]
+		}
+	}
+	mutator := &astWalker{
+		enter: func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.VariableExpression:
+				n.Name = renames[n.Name]
+			case *ast.FunctionLiteral:
+				renameIdentifier(n.Name)
+				if n.ParameterList != nil {
+					for _, param := range n.ParameterList.List {
+						renameIdentifier(param)
+					}
+				}
+			case *ast.CatchStatement:
+				renameIdentifier(n.Parameter)
+			case *ast.LabelledStatement:
+				renameIdentifier(n.Label)
+			case *ast.BranchStatement:
+				renameIdentifier(n.Label)
+			}
+			return true
+		},
+		leave: func(node ast.Node) ast.Node {
+			switch n := node.(type) {
+			case *ast.Identifier:
+				renameIdentifier(n)
+			case *ast.NumberLiteral:
+				if ctx.Rand.Intn(2) == 0 {
+					return numberLiteral(ctx.Rand.Intn(1000))
+				}
+			case *ast.BinaryExpression:
+				if swapped, ok := swappedOperators[n.Operator]; ok && ctx.Rand.Intn(2) == 0 {
+					n.Operator = swapped
+				}
+			}
+			return node
+		},
+	}
+	mutator.walk(fn)
+
+	// 函数声明放进块中在严格模式下不合法，克隆体中顶层的函数声明直接丢弃
+	var body []ast.Statement
+	for _, stmt := range fn.Body.(*ast.BlockStatement).List {
+		if _, ok := stmt.(*ast.FunctionStatement); !ok {
+			body = append(body, stmt)
+		}
+	}
+	return body
+}
+
+// 死代码中可以互换的运算符
+var swappedOperators = map[token.Token]token.Token{
+	token.PLUS:             token.MINUS,
+	token.MINUS:            token.PLUS,
+	token.MULTIPLY:         token.SLASH,
+	token.SLASH:            token.MULTIPLY,
+	token.LESS:             token.GREATER,
+	token.GREATER:          token.LESS,
+	token.STRICT_EQUAL:     token.STRICT_NOT_EQUAL,
+	token.STRICT_NOT_EQUAL: token.STRICT_EQUAL,
+}
+
+// 函数是否为严格模式
+func functionStrict(fn *ast.FunctionLiteral, outer bool) bool {
+	if outer {
+		return true
+	}
+	if body, ok := fn.Body.(*ast.BlockStatement); ok {
+		return isStrict(body.List)
+	}
+	return false
+}
+
+// 语句列表的指令序言中是否有 "use strict"
+func isStrict(list []ast.Statement) bool {
+	directives, _ := splitDirectives(list)
+	for _, stmt := range directives {
+		literal := stmt.(*ast.ExpressionStatement).Expression.(*ast.StringLiteral)
+		if strings.Trim(literal.Literal, `'"`) == "use strict" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package obfuscator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/parser"
+)
+
+// 死代码注入前后的运行结果相同，包括改动了 arguments 的函数
+func TestDeadCodeEquivalence(t *testing.T) {
+	node := requireNode(t)
+	src := strings.Join([]string{
+		"var log = [];",
+		"function sum() { var total = 0; for (var i = 0; i < arguments.length; i++) total += arguments[i]; return total; }",
+		"function param(arguments) { var a = arguments; log.push(typeof a); return a; }",
+		"function assigned() { arguments = [1, 2, 3, 4, 5]; var n = arguments.length; log.push(n); return n; }",
+		"function length() { arguments.length = -1; var n = arguments.length; log.push(n); return n; }",
+		"function declared() { var arguments = 'str'; log.push(arguments.length); return arguments; }",
+		"function inner() { log.push(typeof arguments); function arguments() {} return 1; }",
+		"function caught() { try { throw 1.5; } catch (arguments) { log.push(arguments); var x = arguments; } return x; }",
+		"function strict(a, b) { 'use strict'; var r = a * b; log.push(r); return r; }",
+		"log.push(sum(1, 2, 3), param(7), assigned(), length(), declared(), inner(), caught(), strict(3, 4));",
+		"console.log(log.join());",
+	}, "\n")
+	configs := map[string]Config{
+		"all":     {DeadCodeInjection: true, DeadCodeInjectionThreshold: Threshold(1)},
+		"default": {DeadCodeInjection: true, IdentifierObfuscation: true},
+	}
+	checkEquivalent(t, node, src, configs, 5)
+}
+
+// 只有 arguments 未被改动的函数才注入死代码
+func TestArgumentsIntact(t *testing.T) {
+	cases := []struct {
+		src  string
+		want bool
+	}{
+		{"function f() { return arguments.length; }", true},
+		{"function f() { arguments[0] = 1; }", true},
+		{"function f() { function g(arguments) { arguments = 1; } }", true},
+		{"function f(arguments) {}", false},
+		{"function f() { var arguments; }", false},
+		{"function f() { arguments = []; }", false},
+		{"function f() { arguments.length = 0; }", false},
+		{"function f() { arguments['length']++; }", false},
+		{"function f() { for (arguments in {}) {} }", false},
+		{"function f() { function arguments() {} }", false},
+		{"function f() { try {} catch (arguments) {} }", false},
+		{"function f() { with ({}) {} }", false},
+		{"function f() { eval('arguments = 1'); }", false},
+	}
+	for _, tc := range cases {
+		program, err := parser.ParseFile(nil, "", tc.src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fn := program.Body[0].(*ast.FunctionStatement).Function
+		if got := argumentsIntact(fn); got != tc.want {
+			t.Errorf("argumentsIntact(%s) = %v, want %v", tc.src, got, tc.want)
+		}
+	}
+}
+
+// 比例未设置时使用默认比例，0 时不注入，1 时每条语句都注入
+func TestDeadCodeThreshold(t *testing.T) {
+	src := "function f(a) { var b = a + 1; var c = b * 2; a = c; b = a; c = b; return c; }\nf(1);"
+	for seed := int64(1); seed <= 5; seed++ {
+		obfuscate := func(threshold *float64) string {
+			result, err := Obfuscate(src, Config{DeadCodeInjection: true, DeadCodeInjectionThreshold: threshold, CompactCode: true, Seed: seed})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return result.Code
+		}
+		if obfuscate(nil) != obfuscate(Threshold(defaultDeadCodeThreshold)) {
+			t.Errorf("seed %d: 未设置比例的结果与默认比例不同", seed)
+		}
+		if none := obfuscate(Threshold(0)); strings.Contains(none, "arguments") {
+			t.Errorf("seed %d: 比例 0 时仍然注入:\n%s", seed, none)
+		}
+		// 函数体中的六条语句都被改写为 if-else
+		if all := obfuscate(Threshold(1)); strings.Count(all, "else{") != 6 {
+			t.Errorf("seed %d: 比例 1 时没有全部注入:\n%s", seed, all)
+		}
+	}
+}
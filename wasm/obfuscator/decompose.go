@@ -0,0 +1,425 @@
+package obfuscator
+
+import (
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/token"
+)
+
+func newDecomposeTransform() Transform {
+	return &builtinTransform{
+		name:        "decompose",
+		description: "将复杂表达式拆成一系列临时变量赋值",
+		options: []Option{
+			{Name: "expressionDecomposition", Type: "boolean", Description: "启用表达式分解"},
+		},
+		enabled: func(cfg *Config) bool { return cfg.ExpressionDecomposition },
+		apply: func(ctx *Context) error {
+			decomposeExpressions(ctx)
+			return nil
+		},
+	}
+}
+
+// 表达式分解
+//
+// 复杂表达式的每个中间结果都存入临时变量，按原来的求值顺序写成一串赋值：
+//
+//	x = a * b + f(c);   →   t1 = a * b; t2 = f(c); x = t1 + t2;
+//
+// 语句位置在语句之前插入赋值语句；循环条件、for 的各部分等不能插入语句的位置
+// 使用逗号表达式。后面的操作数有副作用时，前面读取的变量先存入临时变量，保证读到的是原来的值。
+// &&、|| 的右侧和条件表达式的分支只在各自内部分解，不会提前求值。
+// 方法调用的对象和属性保持在一起，参数在原位置以逗号表达式分解，this 和求值顺序都不变。
+// 临时变量声明在所在函数的开头，同一函数中的语句之间复用。
+// with 语句及其中的函数不做处理，eval 的直接调用保持原样。
+func decomposeExpressions(ctx *Context) {
+	// 收集函数体，with 语句中定义的函数跳过
+	var bodies []*[]ast.Statement
+	withDepth := 0
+	walker := &astWalker{
+		enter: func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.WithStatement:
+				withDepth++
+			case *ast.FunctionLiteral:
+				if body, ok := n.Body.(*ast.BlockStatement); ok && withDepth == 0 {
+					bodies = append(bodies, &body.List)
+				}
+			}
+			return true
+		},
+		leave: func(node ast.Node) ast.Node {
+			if _, ok := node.(*ast.WithStatement); ok {
+				withDepth--
+			}
+			return node
+		},
+	}
+	walker.walk(ctx.Program)
+	bodies = append(bodies, &ctx.Program.Body)
+
+	for _, body := range bodies {
+		d := &decomposer{ctx: ctx, isTemp: make(map[string]bool)}
+		directives, rest := splitDirectives(*body)
+		rest = d.statements(rest)
+		if len(d.temps) == 0 {
+			continue
+		}
+		list := make([]ast.Expression, len(d.temps))
+		for i, name := range d.temps {
+			list[i] = &ast.VariableExpression{Name: name}
+		}
+		result := append([]ast.Statement(nil), directives...)
+		result = append(result, &ast.VariableStatement{List: list})
+		*body = append(result, rest...)
+	}
+}
+
+// 单个函数体的分解状态
+type decomposer struct {
+	ctx    *Context
+	temps  []string         // 函数中的全部临时变量
+	isTemp map[string]bool  // 临时变量只赋值一次，读取时不需要再保存
+	used   int              // 当前语句已使用的临时变量数
+	steps  []ast.Expression // 当前表达式之前需要依次执行的步骤
+}
+
+// 取一个当前语句中未使用的临时变量
+func (d *decomposer) temp() *ast.Identifier {
+	if d.used == len(d.temps) {
+		name := d.ctx.newName()
+		d.temps = append(d.temps, name)
+		d.isTemp[name] = true
+	}
+	d.used++
+	return &ast.Identifier{Name: d.temps[d.used-1]}
+}
+
+// 处理语句列表，函数声明作为独立的作用域另行处理
+func (d *decomposer) statements(list []ast.Statement) []ast.Statement {
+	var result []ast.Statement
+	for _, stmt := range list {
+		result = append(result, d.statement(stmt)...)
+	}
+	return result
+}
+
+// 处理单个语句位置，拆出多条语句时放进块中
+func (d *decomposer) single(stmt ast.Statement) ast.Statement {
+	if stmt == nil {
+		return nil
+	}
+	list := d.statement(stmt)
+	if len(list) == 1 {
+		return list[0]
+	}
+	return &ast.BlockStatement{List: list}
+}
+
+// 处理一条语句，返回在原位置依次执行的语句
+func (d *decomposer) statement(stmt ast.Statement) []ast.Statement {
+	d.used = 0
+	d.steps = nil
+	switch s := stmt.(type) {
+	case *ast.ExpressionStatement:
+		s.Expression = d.expression(s.Expression)
+		return d.flush(s)
+	case *ast.VariableStatement:
+		var result []ast.Statement
+		var group []ast.Expression
+		for _, item := range s.List {
+			v, ok := item.(*ast.VariableExpression)
+			if ok && v.Initializer != nil {
+				d.used = 0
+				v.Initializer = d.expression(v.Initializer)
+				if len(d.steps) > 0 {
+					if len(group) > 0 {
+						result = append(result, &ast.VariableStatement{List: group})
+						group = nil
+					}
+					result = append(result, d.flush()...)
+				}
+			}
+			group = append(group, item)
+		}
+		if len(result) == 0 {
+			return []ast.Statement{s}
+		}
+		return append(result, &ast.VariableStatement{List: group})
+	case *ast.ReturnStatement:
+		if s.Argument != nil {
+			s.Argument = d.expression(s.Argument)
+		}
+		return d.flush(s)
+	case *ast.ThrowStatement:
+		s.Argument = d.expression(s.Argument)
+		return d.flush(s)
+	case *ast.IfStatement:
+		s.Test = d.expression(s.Test)
+		pre := d.flush()
+		s.Consequent = d.single(s.Consequent)
+		s.Alternate = d.single(s.Alternate)
+		return append(pre, s)
+	case *ast.SwitchStatement:
+		s.Discriminant = d.expression(s.Discriminant)
+		pre := d.flush()
+		for _, c := range s.Body {
+			c.Consequent = d.statements(c.Consequent)
+		}
+		return append(pre, s)
+	case *ast.ForInStatement:
+		s.Source = d.expression(s.Source)
+		pre := d.flush()
+		s.Body = d.single(s.Body)
+		return append(pre, s)
+	case *ast.WhileStatement:
+		s.Test = d.isolated(s.Test)
+		s.Body = d.single(s.Body)
+	case *ast.DoWhileStatement:
+		s.Test = d.isolated(s.Test)
+		s.Body = d.single(s.Body)
+	case *ast.ForStatement:
+		if seq, ok := s.Initializer.(*ast.SequenceExpression); ok && isVariableList(seq) {
+			for _, item := range seq.Sequence {
+				if v := item.(*ast.VariableExpression); v.Initializer != nil {
+					v.Initializer = d.isolated(v.Initializer)
+				}
+			}
+		} else if s.Initializer != nil {
+			s.Initializer = d.isolated(s.Initializer)
+		}
+		if s.Test != nil {
+			s.Test = d.isolated(s.Test)
+		}
+		if s.Update != nil {
+			s.Update = d.isolated(s.Update)
+		}
+		s.Body = d.single(s.Body)
+	case *ast.LabelledStatement:
+		// 拆出的语句放在标签之前，标签仍然标记原来的语句
+		list := d.statement(s.Statement)
+		s.Statement = list[len(list)-1]
+		return append(list[:len(list)-1], s)
+	case *ast.BlockStatement:
+		s.List = d.statements(s.List)
+	case *ast.TryStatement:
+		s.Body = d.single(s.Body)
+		if s.Catch != nil {
+			s.Catch.Body = d.single(s.Catch.Body)
+		}
+		s.Finally = d.single(s.Finally)
+	}
+	return []ast.Statement{stmt}
+}
+
+// 取出已积累的步骤，转为语句放在 stmts 之前
+func (d *decomposer) flush(stmts ...ast.Statement) []ast.Statement {
+	result := make([]ast.Statement, 0, len(d.steps)+len(stmts))
+	for _, step := range d.steps {
+		result = append(result, &ast.ExpressionStatement{Expression: step})
+	}
+	d.steps = nil
+	return append(result, stmts...)
+}
+
+// 在表达式内部独立分解，步骤与结果组成逗号表达式，不影响外部的求值时机
+func (d *decomposer) isolated(expr ast.Expression) ast.Expression {
+	saved := d.steps
+	d.steps = nil
+	value := d.expression(expr)
+	steps := d.steps
+	d.steps = saved
+	if len(steps) == 0 {
+		return value
+	}
+	return &ast.SequenceExpression{Sequence: append(steps, value)}
+}
+
+// 把表达式的直接子表达式换成临时变量或常量，返回改写后的表达式
+func (d *decomposer) expression(expr ast.Expression) ast.Expression {
+	switch e := expr.(type) {
+	case *ast.BinaryExpression:
+		if e.Operator == token.LOGICAL_AND || e.Operator == token.LOGICAL_OR {
+			e.Left = d.operand(e.Left)
+			e.Right = d.isolated(e.Right)
+			return e
+		}
+		d.operands(&e.Left, &e.Right)
+	case *ast.ConditionalExpression:
+		e.Test = d.operand(e.Test)
+		e.Consequent = d.isolated(e.Consequent)
+		e.Alternate = d.isolated(e.Alternate)
+	case *ast.UnaryExpression:
+		switch {
+		case e.Operator == token.DELETE:
+		case e.Operator == token.INCREMENT || e.Operator == token.DECREMENT:
+			d.reference(e.Operand)
+		case e.Operator == token.TYPEOF && isIdentifier(e.Operand):
+			// 未声明的变量只能直接用在 typeof 中
+		default:
+			e.Operand = d.operand(e.Operand)
+		}
+	case *ast.CallExpression:
+		switch callee := e.Callee.(type) {
+		case *ast.DotExpression, *ast.BracketExpression:
+			// 先取方法再求参数，参数在原位置分解
+			d.reference(callee)
+			for i, arg := range e.ArgumentList {
+				e.ArgumentList[i] = d.isolated(arg)
+			}
+		case *ast.Identifier:
+			if callee.Name == "eval" {
+				return e
+			}
+			d.operands(append([]*ast.Expression{&e.Callee}, argumentPointers(e.ArgumentList)...)...)
+		default:
+			d.operands(append([]*ast.Expression{&e.Callee}, argumentPointers(e.ArgumentList)...)...)
+		}
+	case *ast.NewExpression:
+		d.operands(append([]*ast.Expression{&e.Callee}, argumentPointers(e.ArgumentList)...)...)
+	case *ast.DotExpression:
+		e.Left = d.operand(e.Left)
+	case *ast.BracketExpression:
+		d.operands(&e.Left, &e.Member)
+	case *ast.ArrayLiteral:
+		d.operands(argumentPointers(e.Value)...)
+	case *ast.ObjectLiteral:
+		var values []*ast.Expression
+		for i := range e.Value {
+			values = append(values, &e.Value[i].Value)
+		}
+		d.operands(values...)
+	case *ast.SequenceExpression:
+		// 除最后一项外的结果都被丢弃，直接作为步骤执行；for(;;) 的初始化部分是空序列
+		if len(e.Sequence) == 0 {
+			return e
+		}
+		for _, item := range e.Sequence[:len(e.Sequence)-1] {
+			d.steps = append(d.steps, d.expression(item))
+		}
+		return d.expression(e.Sequence[len(e.Sequence)-1])
+	case *ast.AssignExpression:
+		return d.assignment(e)
+	}
+	return expr
+}
+
+// 赋值：先确定赋值目标，再求右侧的值
+func (d *decomposer) assignment(e *ast.AssignExpression) ast.Expression {
+	parts := d.reference(e.Left)
+	mark := len(d.steps)
+	e.Right = d.operand(e.Right)
+	if len(d.steps) == mark {
+		return e
+	}
+	// 右侧有副作用：目标中的变量先存下来，复合赋值还要先读出原来的值
+	mark = d.captureAt(mark, parts...)
+	if e.Operator != token.ASSIGN {
+		old := d.temp()
+		d.insertStep(mark, assign(old, cloneReference(e.Left)))
+		e.Right = &ast.BinaryExpression{Operator: e.Operator, Left: old, Right: e.Right}
+		e.Operator = token.ASSIGN
+	}
+	return e
+}
+
+// 分解引用（赋值目标、自增对象、方法）中的对象和下标，返回其中可能需要保存的部分
+func (d *decomposer) reference(expr ast.Expression) []*ast.Expression {
+	switch e := expr.(type) {
+	case *ast.DotExpression:
+		e.Left = d.operand(e.Left)
+		return []*ast.Expression{&e.Left}
+	case *ast.BracketExpression:
+		d.operands(&e.Left, &e.Member)
+		return []*ast.Expression{&e.Left, &e.Member}
+	}
+	return nil
+}
+
+// 按顺序把各个操作数换成临时变量；之后的操作数产生步骤时，前面读取的变量先保存
+func (d *decomposer) operands(operands ...*ast.Expression) {
+	marks := make([]int, len(operands))
+	for i, p := range operands {
+		*p = d.operand(*p)
+		marks[i] = len(d.steps)
+	}
+	for i := len(operands) - 2; i >= 0; i-- {
+		if len(d.steps) > marks[i] {
+			d.captureAt(marks[i], operands[i])
+		}
+	}
+}
+
+// 复杂的操作数求值后存入临时变量，变量和常量原样返回
+func (d *decomposer) operand(expr ast.Expression) ast.Expression {
+	switch expr.(type) {
+	case nil, *ast.EmptyExpression, *ast.Identifier, *ast.ThisExpression, *ast.FunctionLiteral,
+		*ast.NullLiteral, *ast.BooleanLiteral, *ast.NumberLiteral, *ast.StringLiteral, *ast.RegExpLiteral:
+		return expr
+	}
+	value := d.expression(expr)
+	t := d.temp()
+	d.steps = append(d.steps, assign(t, value))
+	return t
+}
+
+// 在第 index 个步骤处把读取的变量存入临时变量，返回插入后的位置
+func (d *decomposer) captureAt(index int, operands ...*ast.Expression) int {
+	for _, p := range operands {
+		id, ok := (*p).(*ast.Identifier)
+		if !ok || d.isTemp[id.Name] {
+			continue
+		}
+		t := d.temp()
+		d.insertStep(index, assign(t, id))
+		*p = t
+		index++
+	}
+	return index
+}
+
+func (d *decomposer) insertStep(index int, step ast.Expression) {
+	d.steps = append(d.steps, nil)
+	copy(d.steps[index+1:], d.steps[index:])
+	d.steps[index] = step
+}
+
+// 参数列表（或数组元素）中各项的指针
+func argumentPointers(args []ast.Expression) []*ast.Expression {
+	pointers := make([]*ast.Expression, len(args))
+	for i := range args {
+		pointers[i] = &args[i]
+	}
+	return pointers
+}
+
+// 引用的浅拷贝，对象和下标已经是变量或常量
+func cloneReference(expr ast.Expression) ast.Expression {
+	switch e := expr.(type) {
+	case *ast.DotExpression:
+		return &ast.DotExpression{Left: e.Left, Identifier: e.Identifier}
+	case *ast.BracketExpression:
+		return &ast.BracketExpression{Left: e.Left, Member: e.Member}
+	case *ast.Identifier:
+		return &ast.Identifier{Name: e.Name}
+	}
+	return expr
+}
+
+func assign(target *ast.Identifier, value ast.Expression) ast.Expression {
+	return &ast.AssignExpression{Operator: token.ASSIGN, Left: target, Right: value}
+}
+
+func isIdentifier(expr ast.Expression) bool {
+	_, ok := expr.(*ast.Identifier)
+	return ok
+}
+
+// for 的初始化部分是否为 var 声明
+func isVariableList(seq *ast.SequenceExpression) bool {
+	if len(seq.Sequence) == 0 {
+		return false
+	}
+	_, ok := seq.Sequence[0].(*ast.VariableExpression)
+	return ok
+}
@@ -0,0 +1,52 @@
+package obfuscator
+
+import (
+	"strings"
+	"testing"
+)
+
+// 表达式分解前后的运行结果相同：副作用的顺序、短路求值、this 和 for-in 的初始化都不变
+func TestDecomposeEquivalence(t *testing.T) {
+	node := requireNode(t)
+	src := strings.Join([]string{
+		"var log = [];",
+		"function f(tag, v) { log.push(tag); return v; }",
+		// 求值顺序：后面的操作数修改前面读取的变量
+		"function order() {",
+		"  var a = 1, o = { n: 2, m: function () { return this.n; } };",
+		"  var r = a + (a = 5) * f('x', a) + a++ - f('y', a) + o.m() + o['m']();",
+		"  log.push(r, a);",
+		"  var arr = [f('p', 1), f('q', 2) + a, (a += 2, a)];",
+		"  log.push(arr.join('/'));",
+		"  o.n = f('n', o.n + 1) * f('k', 2);",
+		"  log.push(o.n, f('g', o)[f('h', 'n')] + f('i', 1));",
+		"  var i = 0, b = [0, 0, 0]; b[i++] = i++ + f('z', i);",
+		"  log.push(b.join('/'), i);",
+		"}",
+		"order();",
+		// &&、||、?: 的短路
+		"function shortCircuit(x) {",
+		"  var r = f('a', x) && f('b', x + 1) * 2 || f('c', 3) + f('d', 4);",
+		"  var s = x ? f('t', 1) + f('u', 2) : f('v', 3) * f('w', 4);",
+		"  var u = f('e', null) || (x && f('l', x * 2) + 1);",
+		"  log.push(r, s, u);",
+		"}",
+		"shortCircuit(0); shortCircuit(2);",
+		// 循环条件与 for-in 的初始化
+		"function loops() {",
+		"  var out = [], n = 0;",
+		"  for (var i = f('init', 0) + 1; i < f('cond', 4) - 1; i += f('step', 1) * 1) out.push(i);",
+		"  for (var k = f('kinit', 'x') + 'y' in { a: 1, b: 2 }) out.push(k);",
+		"  for (var j in f('src', { c: 1 })) out.push(j + n++ * 2);",
+		"  while (n * 2 + f('w', 1) < 6) n++;",
+		"  log.push(out.join('/'), n);",
+		"}",
+		"loops();",
+		"console.log(log.join());",
+	}, "\n")
+	configs := map[string]Config{
+		"decompose": {ExpressionDecomposition: true},
+		"combined":  {ExpressionDecomposition: true, ControlFlowFlattening: true, ControlFlowFlatteningThreshold: Threshold(1), IdentifierObfuscation: true},
+	}
+	checkEquivalent(t, node, src, configs, 3)
+}
@@ -0,0 +1,1100 @@
+package obfuscator
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+	"github.com/robertkrimen/otto/token"
+)
+
+// 运算符优先级，数值越大结合越紧
+const (
+	precSequence = iota
+	precAssign
+	precConditional
+	precLogicalOr
+	precLogicalAnd
+	precBitwiseOr
+	precBitwiseXor
+	precBitwiseAnd
+	precEquality
+	precRelational
+	precShift
+	precAdditive
+	precMultiplicative
+	precUnary
+	precPostfix
+	precCall
+	precMember
+	precPrimary
+)
+
+// 代码生成器 - 将 AST 重新输出为 JavaScript 源码
+type codeGenerator struct {
+	buf     strings.Builder
+	compact bool
+	indent  int
+	last    byte
+	noIn    bool
+	source  string
+
+	// comments 是尚未输出的注释，按源码位置排列；lineBreak 表示刚输出了单行注释，
+	// 下一个片段之前必须换行
+	comments  []*ast.Comment
+	lineBreak bool
+
+	// sourceMap 不为 nil 时记录映射；pending 是等待下一个片段的源码位置
+	sourceMap   *sourceMapBuilder
+	pending     file.Idx
+	pendingName string
+
+	// spans 记录指定节点在输出中的字节范围，见 generateCodeSpans
+	spans map[ast.Node][2]int
+}
+
+// 生成代码，sourceMap 不为 nil 时同时记录源码映射
+func generateCode(program *ast.Program, source string, compact bool, sourceMap *sourceMapBuilder) string {
+	return generateCodeSpans(program, source, compact, sourceMap, nil)
+}
+
+// 生成代码，同时记录 spans 中列出的函数字面量和数字字面量在输出中的字节范围
+//
+// spans 的键由调用方预先放入，没有输出的节点保持原值。
+func generateCodeSpans(program *ast.Program, source string, compact bool, sourceMap *sourceMapBuilder, spans map[ast.Node][2]int) string {
+	g := &codeGenerator{
+		compact:   compact,
+		source:    source,
+		comments:  sortedComments(program.Comments),
+		sourceMap: sourceMap,
+		spans:     spans,
+	}
+	for i, stmt := range program.Body {
+		if i > 0 {
+			g.newline()
+		}
+		g.statement(stmt)
+	}
+	// 文件末尾剩余的注释各占一行，输出总是以换行结束
+	for _, comment := range g.comments {
+		g.newline()
+		g.comment(comment)
+	}
+	g.comments = nil
+	g.buf.WriteByte('\n')
+	return g.buf.String()
+}
+
+// 写入一个词法片段，必要时插入空格避免与前一个片段粘连
+func (g *codeGenerator) write(s string) {
+	if s == "" {
+		return
+	}
+	if g.lineBreak {
+		g.lineBreak = false
+		g.buf.WriteByte('\n')
+		g.buf.WriteString(strings.Repeat("    ", g.indent))
+		g.last = '\n'
+	}
+	if g.last != 0 && needsSpace(g.last, s[0]) {
+		g.buf.WriteByte(' ')
+	}
+	if g.pending > 0 {
+		g.sourceMap.add(g.buf.String(), g.pending, g.pendingName)
+		g.pending = 0
+	}
+	g.buf.WriteString(s)
+	g.last = s[len(s)-1]
+}
+
+// 下一个片段对应源码中 idx 处的节点；生成的节点没有位置，沿用外层节点的映射
+func (g *codeGenerator) mark(idx file.Idx) {
+	if g.sourceMap == nil || idx <= 0 {
+		return
+	}
+	g.pending = idx
+	g.pendingName = ""
+}
+
+// 下一个片段是标识符，映射中记录它在源码中的原名
+func (g *codeGenerator) markName(idx file.Idx) {
+	g.mark(idx)
+	if g.pending == idx && idx > 0 {
+		g.pendingName = g.sourceMap.identifierAt(idx)
+	}
+}
+
+// 写入标识符
+func (g *codeGenerator) identifier(id *ast.Identifier) {
+	g.leadingComments(id.Idx, false)
+	g.markName(id.Idx)
+	g.write(id.Name)
+}
+
+// 非压缩模式下输出空格
+func (g *codeGenerator) space() {
+	if !g.compact && !g.lineBreak {
+		g.buf.WriteByte(' ')
+		g.last = ' '
+	}
+}
+
+// 非压缩模式下换行并缩进；单行注释之后即使在压缩模式下也必须换行
+func (g *codeGenerator) newline() {
+	if g.compact && !g.lineBreak {
+		return
+	}
+	g.lineBreak = false
+	g.buf.WriteByte('\n')
+	g.buf.WriteString(strings.Repeat("    ", g.indent))
+	g.last = '\n'
+}
+
+// 判断两个相邻字符之间是否需要空格
+func needsSpace(prev, next byte) bool {
+	if isIdentifierByte(prev) && isIdentifierByte(next) {
+		return true
+	}
+	switch {
+	case prev == '+' && next == '+',
+		prev == '-' && next == '-',
+		prev == '/' && (next == '/' || next == '*'),
+		prev == '<' && next == '!':
+		return true
+	}
+	return false
+}
+
+func isIdentifierByte(c byte) bool {
+	return c == '_' || c == '$' || c == '\\' || c >= 0x80 ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// 按源码位置排列的全部注释
+func sortedComments(comments ast.CommentMap) []*ast.Comment {
+	var list []*ast.Comment
+	for _, items := range comments {
+		list = append(list, items...)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Begin < list[j].Begin })
+	return list
+}
+
+// 输出源码中位于 idx 之前、尚未输出的注释
+//
+// 注释随其后最近的一个输出节点输出：ownLine 为真时每条注释单独一行（语句之前），
+// 否则与代码写在同一行（表达式、参数之前）。生成的节点没有位置，不输出注释。
+func (g *codeGenerator) leadingComments(idx file.Idx, ownLine bool) {
+	for len(g.comments) > 0 && idx > 0 && g.comments[0].Begin < idx {
+		g.comment(g.comments[0])
+		g.comments = g.comments[1:]
+		if ownLine {
+			g.newline()
+		} else {
+			g.space()
+		}
+	}
+}
+
+// 输出与语句结尾在源码中同一行的注释，如 x(); // c
+func (g *codeGenerator) trailingComments(stmt ast.Statement) {
+	end := int(sourceEnd(stmt)) - 1
+	for len(g.comments) > 0 && end >= 0 {
+		begin := int(g.comments[0].Begin) - 1
+		if begin < end || begin > len(g.source) || strings.Trim(g.source[end:begin], " \t;") != "" {
+			return
+		}
+		g.space()
+		g.comment(g.comments[0])
+		g.comments = g.comments[1:]
+		end = begin
+	}
+}
+
+// 输出括号（参数列表、数组）结束之前剩余的注释，与代码写在同一行
+func (g *codeGenerator) innerComments(end file.Idx) {
+	for g.hasCommentBefore(end) {
+		g.space()
+		g.comment(g.comments[0])
+		g.comments = g.comments[1:]
+	}
+}
+
+// 输出容器（语句块、switch、对象字面量）结束之前剩余的注释，每条单独一行
+func (g *codeGenerator) closingComments(end file.Idx) {
+	g.indent++
+	for g.hasCommentBefore(end) {
+		g.newline()
+		g.comment(g.comments[0])
+		g.comments = g.comments[1:]
+	}
+	g.indent--
+}
+
+// 源码中 end 之前是否还有未输出的注释
+func (g *codeGenerator) hasCommentBefore(end file.Idx) bool {
+	return len(g.comments) > 0 && end > 0 && g.comments[0].Begin < end
+}
+
+// 节点在源码中的结束位置，没有位置的生成节点返回 0
+//
+// 变换改写过的节点可能缺少子节点，计算结束位置出错时同样视为没有位置。
+func sourceEnd(node ast.Node) (idx file.Idx) {
+	defer func() {
+		if recover() != nil {
+			idx = 0
+		}
+	}()
+	if node.Idx0() <= 0 {
+		return 0
+	}
+	return node.Idx1()
+}
+
+func (g *codeGenerator) comment(comment *ast.Comment) {
+	offset := int(comment.Begin) - 1
+	if offset >= 0 && offset+1 < len(g.source) && g.source[offset:offset+2] == "//" {
+		// 单行注释必须以换行结束，换行推迟到下一个片段之前
+		g.write("//" + comment.Text)
+		g.lineBreak = true
+		return
+	}
+	g.write("/*" + comment.Text + "*/")
+}
+
+// 输出语句
+func (g *codeGenerator) statement(stmt ast.Statement) {
+	if len(g.comments) > 0 {
+		g.leadingComments(stmt.Idx0(), true)
+		defer g.trailingComments(stmt)
+	}
+	if g.sourceMap != nil {
+		g.mark(stmt.Idx0())
+	}
+
+	switch s := stmt.(type) {
+	case *ast.BlockStatement:
+		g.block(s.List, s.RightBrace)
+	case *ast.BranchStatement:
+		g.write(s.Token.String())
+		if s.Label != nil {
+			g.space()
+			g.write(s.Label.Name)
+		}
+		g.write(";")
+	case *ast.DebuggerStatement:
+		g.write("debugger;")
+	case *ast.DoWhileStatement:
+		g.write("do")
+		g.body(s.Body)
+		if _, ok := s.Body.(*ast.BlockStatement); ok {
+			g.space()
+		} else {
+			g.newline()
+		}
+		g.write("while")
+		g.space()
+		g.write("(")
+		g.expression(s.Test, precSequence)
+		g.write(");")
+	case *ast.EmptyStatement:
+		g.write(";")
+	case *ast.ExpressionStatement:
+		if startsWithFunctionOrObject(s.Expression) {
+			g.write("(")
+			g.expression(s.Expression, precSequence)
+			g.write(")")
+		} else {
+			g.expression(s.Expression, precSequence)
+		}
+		g.write(";")
+	case *ast.ForInStatement:
+		g.write("for")
+		g.space()
+		g.write("(")
+		g.noIn = true
+		if v, ok := s.Into.(*ast.VariableExpression); ok {
+			g.write("var ")
+			g.variable(v)
+		} else {
+			g.expression(s.Into, precPostfix)
+		}
+		g.noIn = false
+		g.write(" in ")
+		g.expression(s.Source, precSequence)
+		g.write(")")
+		g.body(s.Body)
+	case *ast.ForStatement:
+		g.write("for")
+		g.space()
+		g.write("(")
+		g.noIn = true
+		g.forInitializer(s.Initializer)
+		g.noIn = false
+		g.write(";")
+		if s.Test != nil {
+			g.space()
+			g.expression(s.Test, precSequence)
+		}
+		g.write(";")
+		if s.Update != nil {
+			g.space()
+			g.expression(s.Update, precSequence)
+		}
+		g.write(")")
+		g.body(s.Body)
+	case *ast.FunctionStatement:
+		g.function(s.Function)
+	case *ast.IfStatement:
+		g.ifStatement(s)
+	case *ast.LabelledStatement:
+		g.write(s.Label.Name)
+		g.write(":")
+		g.space()
+		g.statement(s.Statement)
+	case *ast.ReturnStatement:
+		g.write("return")
+		if s.Argument != nil {
+			g.space()
+			g.expression(s.Argument, precSequence)
+		}
+		g.write(";")
+	case *ast.SwitchStatement:
+		g.write("switch")
+		g.space()
+		g.write("(")
+		g.expression(s.Discriminant, precSequence)
+		g.write(")")
+		g.space()
+		g.write("{")
+		g.indent++
+		for _, c := range s.Body {
+			g.newline()
+			g.caseClause(c)
+		}
+		g.indent--
+		g.closingComments(s.RightBrace)
+		g.newline()
+		g.write("}")
+	case *ast.ThrowStatement:
+		g.write("throw")
+		g.space()
+		g.expression(s.Argument, precSequence)
+		g.write(";")
+	case *ast.TryStatement:
+		g.write("try")
+		g.space()
+		g.statement(s.Body)
+		if s.Catch != nil {
+			g.space()
+			g.write("catch")
+			g.space()
+			g.write("(")
+			g.identifier(s.Catch.Parameter)
+			g.write(")")
+			g.space()
+			g.statement(s.Catch.Body)
+		}
+		if s.Finally != nil {
+			g.space()
+			g.write("finally")
+			g.space()
+			g.statement(s.Finally)
+		}
+	case *ast.VariableStatement:
+		g.write("var ")
+		g.variableList(s.List)
+		g.write(";")
+	case *ast.WhileStatement:
+		g.write("while")
+		g.space()
+		g.write("(")
+		g.expression(s.Test, precSequence)
+		g.write(")")
+		g.body(s.Body)
+	case *ast.WithStatement:
+		g.write("with")
+		g.space()
+		g.write("(")
+		g.expression(s.Object, precSequence)
+		g.write(")")
+		g.body(s.Body)
+	}
+}
+
+// 输出语句块，end 是源码中右花括号的位置
+func (g *codeGenerator) block(list []ast.Statement, end file.Idx) {
+	g.write("{")
+	if len(list) == 0 && !g.hasCommentBefore(end) {
+		g.write("}")
+		return
+	}
+	g.indent++
+	for _, stmt := range list {
+		g.newline()
+		g.statement(stmt)
+	}
+	g.indent--
+	g.closingComments(end)
+	g.newline()
+	g.write("}")
+}
+
+// 输出控制语句的子语句
+func (g *codeGenerator) body(stmt ast.Statement) {
+	if block, ok := stmt.(*ast.BlockStatement); ok {
+		g.space()
+		g.block(block.List, block.RightBrace)
+		return
+	}
+	g.indent++
+	g.newline()
+	g.statement(stmt)
+	g.indent--
+}
+
+func (g *codeGenerator) ifStatement(s *ast.IfStatement) {
+	g.write("if")
+	g.space()
+	g.write("(")
+	g.expression(s.Test, precSequence)
+	g.write(")")
+	if s.Alternate == nil {
+		g.body(s.Consequent)
+		return
+	}
+
+	// 有 else 分支时始终使用语句块，避免悬挂 else 的歧义
+	consequent, ok := s.Consequent.(*ast.BlockStatement)
+	if !ok {
+		consequent = &ast.BlockStatement{List: []ast.Statement{s.Consequent}}
+	}
+	g.body(consequent)
+	g.space()
+	g.write("else")
+	if alternate, ok := s.Alternate.(*ast.IfStatement); ok {
+		g.write(" ")
+		g.ifStatement(alternate)
+		return
+	}
+	g.body(s.Alternate)
+}
+
+func (g *codeGenerator) caseClause(c *ast.CaseStatement) {
+	if c.Test == nil {
+		g.write("default:")
+	} else {
+		g.write("case")
+		g.space()
+		g.expression(c.Test, precSequence)
+		g.write(":")
+	}
+	g.indent++
+	for _, stmt := range c.Consequent {
+		g.newline()
+		g.statement(stmt)
+	}
+	g.indent--
+}
+
+func (g *codeGenerator) forInitializer(init ast.Expression) {
+	if init == nil {
+		return
+	}
+	if seq, ok := init.(*ast.SequenceExpression); ok {
+		if len(seq.Sequence) == 0 {
+			return
+		}
+		if _, ok := seq.Sequence[0].(*ast.VariableExpression); ok {
+			g.write("var ")
+			g.variableList(seq.Sequence)
+			return
+		}
+	}
+	g.expression(init, precSequence)
+}
+
+func (g *codeGenerator) variableList(list []ast.Expression) {
+	for i, item := range list {
+		if i > 0 {
+			g.write(",")
+			g.space()
+		}
+		if v, ok := item.(*ast.VariableExpression); ok {
+			g.variable(v)
+		} else {
+			g.expression(item, precAssign)
+		}
+	}
+}
+
+func (g *codeGenerator) variable(v *ast.VariableExpression) {
+	g.markName(v.Idx)
+	g.write(v.Name)
+	if v.Initializer != nil {
+		g.space()
+		g.write("=")
+		g.space()
+		g.expression(v.Initializer, precAssign)
+	}
+}
+
+func (g *codeGenerator) function(fn *ast.FunctionLiteral) {
+	g.write("function")
+	if _, ok := g.spans[fn]; ok {
+		start := g.buf.Len() - len("function")
+		defer func() { g.spans[fn] = [2]int{start, g.buf.Len()} }()
+	}
+	if fn.Name != nil {
+		g.write(" ")
+		g.identifier(fn.Name)
+	}
+	g.write("(")
+	if fn.ParameterList != nil {
+		for i, param := range fn.ParameterList.List {
+			if i > 0 {
+				g.write(",")
+				g.space()
+			}
+			g.identifier(param)
+		}
+		g.innerComments(fn.ParameterList.Closing)
+	}
+	g.write(")")
+	g.space()
+
+	// 函数体内部重新允许 in 运算符
+	noIn := g.noIn
+	g.noIn = false
+	if body, ok := fn.Body.(*ast.BlockStatement); ok {
+		g.block(body.List, body.RightBrace)
+	} else {
+		g.block([]ast.Statement{fn.Body}, 0)
+	}
+	g.noIn = noIn
+}
+
+// 输出表达式，优先级低于 prec 时加括号
+func (g *codeGenerator) expression(expr ast.Expression, prec int) {
+	own := expressionPrecedence(expr)
+	if b, ok := expr.(*ast.BinaryExpression); ok && g.noIn && b.Operator == token.IN {
+		own = -1
+	}
+	if own < prec {
+		g.write("(")
+		noIn := g.noIn
+		g.noIn = false
+		g.expressionBody(expr)
+		g.noIn = noIn
+		g.write(")")
+		return
+	}
+	g.expressionBody(expr)
+}
+
+func (g *codeGenerator) expressionBody(expr ast.Expression) {
+	if len(g.comments) > 0 {
+		g.leadingComments(expr.Idx0(), false)
+	}
+	if g.sourceMap != nil {
+		g.mark(expr.Idx0())
+	}
+
+	switch e := expr.(type) {
+	case *ast.ArrayLiteral:
+		g.write("[")
+		for i, item := range e.Value {
+			if i > 0 {
+				g.write(",")
+				g.space()
+			}
+			if _, hole := item.(*ast.EmptyExpression); hole || item == nil {
+				continue
+			}
+			g.expression(item, precAssign)
+		}
+		g.innerComments(e.RightBracket)
+		if n := len(e.Value); n > 0 {
+			if _, hole := e.Value[n-1].(*ast.EmptyExpression); hole || e.Value[n-1] == nil {
+				g.write(",")
+			}
+		}
+		g.write("]")
+	case *ast.AssignExpression:
+		g.expression(e.Left, precPostfix)
+		g.space()
+		if e.Operator == token.ASSIGN {
+			g.write("=")
+		} else {
+			g.write(e.Operator.String() + "=")
+		}
+		g.space()
+		g.expression(e.Right, precAssign)
+	case *ast.BinaryExpression:
+		p := binaryPrecedence(e.Operator)
+		g.expression(e.Left, p)
+		op := e.Operator.String()
+		if isIdentifierByte(op[0]) || !g.compact {
+			g.write(" ")
+			g.write(op)
+			g.write(" ")
+		} else {
+			g.write(op)
+		}
+		g.expression(e.Right, p+1)
+	case *ast.BooleanLiteral:
+		if e.Value {
+			g.write("true")
+		} else {
+			g.write("false")
+		}
+	case *ast.BracketExpression:
+		g.memberObject(e.Left)
+		g.write("[")
+		g.expression(e.Member, precSequence)
+		g.write("]")
+	case *ast.CallExpression:
+		g.expression(e.Callee, precCall)
+		g.arguments(e.ArgumentList, e.RightParenthesis)
+	case *ast.ConditionalExpression:
+		g.expression(e.Test, precLogicalOr)
+		g.space()
+		g.write("?")
+		g.space()
+		g.expression(e.Consequent, precAssign)
+		g.space()
+		g.write(":")
+		g.space()
+		g.expression(e.Alternate, precAssign)
+	case *ast.DotExpression:
+		g.memberObject(e.Left)
+		g.write(".")
+		g.mark(e.Identifier.Idx)
+		g.write(e.Identifier.Name)
+	case *ast.EmptyExpression:
+	case *ast.FunctionLiteral:
+		g.function(e)
+	case *ast.Identifier:
+		g.identifier(e)
+	case *ast.NewExpression:
+		g.write("new ")
+		if containsCall(e.Callee) {
+			g.write("(")
+			g.expressionBody(e.Callee)
+			g.write(")")
+		} else {
+			g.expression(e.Callee, precMember)
+		}
+		g.arguments(e.ArgumentList, e.RightParenthesis)
+	case *ast.NullLiteral:
+		g.write("null")
+	case *ast.NumberLiteral:
+		text := numberLiteralText(e)
+		g.write(text)
+		if _, ok := g.spans[e]; ok {
+			g.spans[e] = [2]int{g.buf.Len() - len(text), g.buf.Len()}
+		}
+	case *ast.ObjectLiteral:
+		g.object(e)
+	case *ast.RegExpLiteral:
+		// 带标志位时解析器给出的 Literal 会多带下一个词法单元的开头，按模式和标志重新拼出
+		g.write("/" + e.Pattern + "/" + e.Flags)
+		if g.last == '/' {
+			// 正则后紧跟标识符会被当成标志位
+			g.last = 'a'
+		}
+	case *ast.SequenceExpression:
+		for i, item := range e.Sequence {
+			if i > 0 {
+				g.write(",")
+				g.space()
+			}
+			g.expression(item, precAssign)
+		}
+	case *ast.StringLiteral:
+		if e.Literal != "" {
+			g.write(e.Literal)
+		} else {
+			g.write(quoteString(e.Value))
+		}
+	case *ast.ThisExpression:
+		g.write("this")
+	case *ast.UnaryExpression:
+		op := e.Operator.String()
+		if e.Postfix {
+			g.expression(e.Operand, precPostfix)
+			g.write(op)
+			return
+		}
+		g.write(op)
+		if isIdentifierByte(op[0]) {
+			g.space()
+		}
+		g.expression(e.Operand, precUnary)
+	case *ast.VariableExpression:
+		g.variable(e)
+	}
+}
+
+// 成员访问的对象部分
+func (g *codeGenerator) memberObject(left ast.Expression) {
+	if _, ok := left.(*ast.NumberLiteral); ok {
+		g.write("(")
+		g.expressionBody(left)
+		g.write(")")
+		return
+	}
+	g.expression(left, precCall)
+}
+
+// 输出参数列表，end 是源码中右括号的位置
+func (g *codeGenerator) arguments(args []ast.Expression, end file.Idx) {
+	g.write("(")
+	for i, arg := range args {
+		if i > 0 {
+			g.write(",")
+			g.space()
+		}
+		g.expression(arg, precAssign)
+	}
+	g.innerComments(end)
+	g.write(")")
+}
+
+func (g *codeGenerator) object(o *ast.ObjectLiteral) {
+	if len(o.Value) == 0 && !g.hasCommentBefore(o.RightBrace) {
+		g.write("{}")
+		return
+	}
+	g.write("{")
+	g.indent++
+	for i, prop := range o.Value {
+		if i > 0 {
+			g.write(",")
+		}
+		g.newline()
+		fn, accessor := prop.Value.(*ast.FunctionLiteral)
+		if accessor && (prop.Kind == "get" || prop.Kind == "set") {
+			g.write(prop.Kind)
+			g.write(" ")
+			g.write(propertyKey(prop.Key))
+			g.write("(")
+			if fn.ParameterList != nil {
+				for j, param := range fn.ParameterList.List {
+					if j > 0 {
+						g.write(",")
+					}
+					g.write(param.Name)
+				}
+			}
+			g.write(")")
+			g.space()
+			if body, ok := fn.Body.(*ast.BlockStatement); ok {
+				g.block(body.List, body.RightBrace)
+			}
+			continue
+		}
+		g.write(propertyKey(prop.Key))
+		g.write(":")
+		g.space()
+		g.expression(prop.Value, precAssign)
+	}
+	g.indent--
+	g.closingComments(o.RightBrace)
+	g.newline()
+	g.write("}")
+}
+
+// 表达式语句不能以 function 或 { 开头
+func startsWithFunctionOrObject(expr ast.Expression) bool {
+	for {
+		switch e := expr.(type) {
+		case *ast.FunctionLiteral, *ast.ObjectLiteral:
+			return true
+		case *ast.AssignExpression:
+			expr = e.Left
+		case *ast.BinaryExpression:
+			expr = e.Left
+		case *ast.BracketExpression:
+			expr = e.Left
+		case *ast.CallExpression:
+			expr = e.Callee
+		case *ast.ConditionalExpression:
+			expr = e.Test
+		case *ast.DotExpression:
+			expr = e.Left
+		case *ast.SequenceExpression:
+			if len(e.Sequence) == 0 {
+				return false
+			}
+			expr = e.Sequence[0]
+		case *ast.UnaryExpression:
+			if !e.Postfix {
+				return false
+			}
+			expr = e.Operand
+		default:
+			return false
+		}
+	}
+}
+
+// new 的被调用者中如果含有调用表达式，需要加括号
+func containsCall(expr ast.Expression) bool {
+	for {
+		switch e := expr.(type) {
+		case *ast.CallExpression:
+			return true
+		case *ast.DotExpression:
+			expr = e.Left
+		case *ast.BracketExpression:
+			expr = e.Left
+		default:
+			return false
+		}
+	}
+}
+
+func expressionPrecedence(expr ast.Expression) int {
+	switch e := expr.(type) {
+	case *ast.SequenceExpression:
+		return precSequence
+	case *ast.AssignExpression:
+		return precAssign
+	case *ast.ConditionalExpression:
+		return precConditional
+	case *ast.BinaryExpression:
+		return binaryPrecedence(e.Operator)
+	case *ast.UnaryExpression:
+		if e.Postfix {
+			return precPostfix
+		}
+		return precUnary
+	case *ast.CallExpression:
+		return precCall
+	case *ast.DotExpression, *ast.BracketExpression, *ast.NewExpression:
+		return precMember
+	case *ast.NumberLiteral:
+		if strings.HasPrefix(numberLiteralText(e), "-") {
+			return precUnary
+		}
+		return precPrimary
+	case *ast.FunctionLiteral:
+		// 函数字面量作为被调用者时需要括号
+		return precMember
+	default:
+		return precPrimary
+	}
+}
+
+func binaryPrecedence(op token.Token) int {
+	switch op {
+	case token.LOGICAL_OR:
+		return precLogicalOr
+	case token.LOGICAL_AND:
+		return precLogicalAnd
+	case token.OR:
+		return precBitwiseOr
+	case token.EXCLUSIVE_OR:
+		return precBitwiseXor
+	case token.AND:
+		return precBitwiseAnd
+	case token.EQUAL, token.NOT_EQUAL, token.STRICT_EQUAL, token.STRICT_NOT_EQUAL:
+		return precEquality
+	case token.LESS, token.GREATER, token.LESS_OR_EQUAL, token.GREATER_OR_EQUAL, token.INSTANCEOF, token.IN:
+		return precRelational
+	case token.SHIFT_LEFT, token.SHIFT_RIGHT, token.UNSIGNED_SHIFT_RIGHT:
+		return precShift
+	case token.PLUS, token.MINUS:
+		return precAdditive
+	default:
+		return precMultiplicative
+	}
+}
+
+// 数字字面量的源码形式
+func numberLiteralText(n *ast.NumberLiteral) string {
+	if n.Literal != "" {
+		return n.Literal
+	}
+	switch v := n.Value.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return formatNumber(v)
+	}
+	return "0"
+}
+
+// 按 JavaScript 的习惯格式化浮点数
+func formatNumber(f float64) string {
+	if math.IsNaN(f) {
+		return "NaN"
+	}
+	if math.IsInf(f, 0) {
+		if f < 0 {
+			return "-Infinity"
+		}
+		return "Infinity"
+	}
+	if f == math.Trunc(f) && math.Abs(f) < 1e21 {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// 对象属性名：合法标识符直接输出，否则使用字符串
+func propertyKey(key string) string {
+	if isIdentifierName(key) {
+		return key
+	}
+	return quoteString(key)
+}
+
+// 把数字写成的属性名换成对应的属性名字符串
+//
+// 解析器对数字键和字符串键都只保留 Key 字符串，{0x10: 1} 与 {'0x10': 1} 无法区分，
+// 原样按字符串输出会改变属性名。解析之后立即根据源码判断：值之前的冒号前面不是引号的键是数字，
+// 换成 JavaScript 中该数字转为字符串的结果（0x10 → 16，1.50 → 1.5）。
+func normalizeNumericKeys(program *ast.Program, source string) {
+	walker := &astWalker{
+		enter: func(node ast.Node) bool {
+			object, ok := node.(*ast.ObjectLiteral)
+			if !ok {
+				return true
+			}
+			for i := range object.Value {
+				prop := &object.Value[i]
+				if prop.Kind != "value" || !numericKeyWritten(prop, source) {
+					continue
+				}
+				if name, ok := numericPropertyName(prop.Key); ok {
+					prop.Key = name
+				}
+			}
+			return true
+		},
+	}
+	walker.walk(program)
+}
+
+// 属性键在源码中是否写成数字
+func numericKeyWritten(prop *ast.Property, source string) bool {
+	if prop.Key == "" || prop.Key[0] != '.' && (prop.Key[0] < '0' || prop.Key[0] > '9') {
+		return false
+	}
+	offset := int(prop.Value.Idx0()) - 1
+	if offset <= 0 || offset > len(source) {
+		return false
+	}
+	text := strings.TrimRight(source[:offset], " \t\n\r\v\f(")
+	if !strings.HasSuffix(text, ":") {
+		return false
+	}
+	text = strings.TrimRight(text[:len(text)-1], " \t\n\r\v\f")
+	// 数字以数字、字母（十六进制或指数）或小数点结尾；引号或注释说明不是数字键
+	last := text[len(text)-1]
+	return isIdentifierByte(last) || last == '.'
+}
+
+// 数字字面量作为属性名时的字符串
+func numericPropertyName(literal string) (string, bool) {
+	// 与解析器一致：0x 开头为十六进制，0 开头的整数为八进制
+	if n, err := strconv.ParseInt(literal, 0, 64); err == nil {
+		return numberToString(float64(n)), true
+	}
+	if f, err := strconv.ParseFloat(literal, 64); err == nil {
+		return numberToString(f), true
+	}
+	return "", false
+}
+
+// JavaScript 中数字转为字符串的结果（Number::toString）
+func numberToString(f float64) string {
+	if f == 0 {
+		return "0"
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return formatNumber(f)
+	}
+	sign := ""
+	if f < 0 {
+		sign, f = "-", -f
+	}
+	// 最短的有效数字 digits 与小数点位置 n：f = 0.digits × 10^n
+	mantissa, exponent, _ := strings.Cut(strconv.FormatFloat(f, 'e', -1, 64), "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	e, _ := strconv.Atoi(exponent)
+	k, n := len(digits), e+1
+	switch {
+	case k <= n && n <= 21:
+		return sign + digits + strings.Repeat("0", n-k)
+	case 0 < n && n <= 21:
+		return sign + digits[:n] + "." + digits[n:]
+	case -6 < n && n <= 0:
+		return sign + "0." + strings.Repeat("0", -n) + digits
+	}
+	result := digits[:1]
+	if k > 1 {
+		result += "." + digits[1:]
+	}
+	if n-1 >= 0 {
+		return sign + result + "e+" + strconv.Itoa(n-1)
+	}
+	return sign + result + "e-" + strconv.Itoa(1-n)
+}
+
+func isIdentifierName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_' || r == '$' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+		case i > 0 && r >= '0' && r <= '9':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// 生成单引号字符串字面量
+func quoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		switch r {
+		case '\'':
+			b.WriteString(`\'`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case 0x2028, 0x2029:
+			b.WriteString(`\u` + strconv.FormatInt(int64(r), 16))
+		default:
+			if r < 0x20 || r == 0x7f {
+				b.WriteString(`\x`)
+				if r < 0x10 {
+					b.WriteByte('0')
+				}
+				b.WriteString(strconv.FormatInt(int64(r), 16))
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
@@ -0,0 +1,86 @@
+package obfuscator
+
+import "testing"
+
+// 保留注释时每条注释都输出在最近的节点旁，输出以换行结束
+func TestPreserveComments(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"parameter", "function f(/* p */ a, b /* q */) {}", "function f(/* p */ a, b /* q */) {}\n"},
+		{"after last statement", "x(); /* after */", "x(); /* after */\n"},
+		{"trailing line comment", "x(); // c\ny();", "x(); // c\ny();\n"},
+		{"leading comment", "// c\nx();", "// c\nx();\n"},
+		{"end of block", "if (a) {\n  b();\n  // end\n}", "if (a) {\n    b();\n    // end\n}\n"},
+		{"empty function", "function g() {\n  // only\n}", "function g() {\n    // only\n}\n"},
+		{"argument", "f(a, /* x */ b /* y */);", "f(a, /* x */ b /* y */);\n"},
+		{"end of file", "x();\n// tail", "x();\n// tail\n"},
+	}
+	for _, tc := range cases {
+		result, err := Obfuscate(tc.src, Config{PreserveComments: true})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if result.Code != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, result.Code, tc.want)
+		}
+	}
+
+	// 压缩输出中单行注释之后仍然换行
+	result, err := Obfuscate("x(); // c\ny();", Config{PreserveComments: true, CompactCode: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "x();// c\ny();\n"; result.Code != want {
+		t.Errorf("compact: got %q, want %q", result.Code, want)
+	}
+}
+
+// 数字写成的属性键输出为对应的属性名，与同样写法的字符串键区分开
+func TestNumericObjectKeys(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"o = {0x10: 1};", "o={'16':1};\n"},
+		{"o = {'0x10': 1};", "o={'0x10':1};\n"},
+		{"o = {1.50: 1, .5: 2};", "o={'1.5':1,'0.5':2};\n"},
+		{"o = {1e21: 1, 0.0000001: (2)};", "o={'1e+21':1,'1e-7':2};\n"},
+		{"o = {123456789012345678901234: 1};", "o={'1.2345678901234569e+23':1};\n"},
+	}
+	for _, tc := range cases {
+		result, err := Obfuscate(tc.src, Config{CompactCode: true})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.src, err)
+		}
+		if result.Code != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.src, result.Code, tc.want)
+		}
+	}
+}
+
+// 带标志位的正则表达式后面紧跟其他词法单元时原样输出
+func TestRegExpLiteral(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"x = /a/g instanceof RegExp;", "x=/a/g instanceof RegExp;\n"},
+		{"x = /a/g in o;", "x=/a/g in o;\n"},
+		{"x = /a/gi && 1;", "x=/a/gi&&1;\n"},
+		{"f(/a\\/b[/]/im);", "f(/a\\/b[/]/im);\n"},
+		{"x = /a/g\ny = 1;", "x=/a/g;y=1;\n"},
+		{"x = /a/ instanceof RegExp;", "x=/a/ instanceof RegExp;\n"},
+	}
+	for _, tc := range cases {
+		result, err := Obfuscate(tc.src, Config{CompactCode: true})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.src, err)
+		}
+		if result.Code != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.src, result.Code, tc.want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package obfuscator
+
+import (
+	"errors"
+	"strings"
+)
+
+// 目标运行环境
+const (
+	TargetBrowser       = "browser"
+	TargetNode          = "node"
+	TargetWebWorker     = "webworker"
+	TargetServiceWorker = "service-worker"
+	TargetNeutral       = "neutral"
+)
+
+// ECMAScript 标准内置全局名，所有环境都有
+const esGlobalNames = `
+	globalThis Infinity NaN undefined eval isFinite isNaN parseFloat parseInt
+	decodeURI decodeURIComponent encodeURI encodeURIComponent escape unescape
+	AggregateError Array ArrayBuffer Atomics BigInt BigInt64Array BigUint64Array
+	Boolean DataView Date Error EvalError FinalizationRegistry Float32Array
+	Float64Array Function Int8Array Int16Array Int32Array Intl Iterator JSON Map
+	Math Number Object Promise Proxy RangeError ReferenceError Reflect RegExp Set
+	SharedArrayBuffer String Symbol SyntaxError TypeError Uint8Array
+	Uint8ClampedArray Uint16Array Uint32Array URIError WeakMap WeakRef WeakSet
+	console
+`
+
+// 浏览器、Worker 和 Node.js 共有的宿主 API
+const hostGlobalNames = `
+	setTimeout clearTimeout setInterval clearInterval queueMicrotask
+	structuredClone atob btoa reportError
+	fetch Request Response Headers FormData URL URLSearchParams
+	TextEncoder TextDecoder TextEncoderStream TextDecoderStream
+	AbortController AbortSignal Event EventTarget CustomEvent DOMException
+	MessageChannel MessagePort MessageEvent BroadcastChannel
+	Blob File crypto Crypto CryptoKey SubtleCrypto
+	performance Performance PerformanceEntry PerformanceMark PerformanceMeasure
+	PerformanceObserver PerformanceObserverEntryList PerformanceResourceTiming
+	ReadableStream ReadableStreamDefaultReader ReadableStreamBYOBReader
+	ReadableStreamDefaultController ReadableByteStreamController
+	WritableStream WritableStreamDefaultWriter WritableStreamDefaultController
+	TransformStream TransformStreamDefaultController
+	ByteLengthQueuingStrategy CountQueuingStrategy
+	CompressionStream DecompressionStream WebAssembly WebSocket navigator Navigator
+`
+
+// 浏览器与各类 Worker 共有的 Web API
+const webGlobalNames = `
+	self location origin isSecureContext crossOriginIsolated
+	addEventListener removeEventListener dispatchEvent
+	caches Cache CacheStorage indexedDB IDBCursor IDBCursorWithValue IDBDatabase
+	IDBFactory IDBIndex IDBKeyRange IDBObjectStore IDBOpenDBRequest IDBRequest
+	IDBTransaction IDBVersionChangeEvent
+	XMLHttpRequest XMLHttpRequestEventTarget XMLHttpRequestUpload
+	EventSource CloseEvent ErrorEvent ProgressEvent PromiseRejectionEvent
+	FileReader FileReaderSync FileList ImageData ImageBitmap createImageBitmap
+	OffscreenCanvas OffscreenCanvasRenderingContext2D CanvasGradient CanvasPattern
+	Path2D TextMetrics DOMMatrix DOMMatrixReadOnly DOMPoint DOMPointReadOnly DOMQuad
+	DOMRect DOMRectReadOnly DOMStringList WebGLRenderingContext WebGL2RenderingContext
+	fonts FontFace FontFaceSet Notification Worker Lock LockManager StorageManager
+	NetworkInformation PushManager PushSubscription ServiceWorkerRegistration
+	ServiceWorker MediaCapabilities Permissions PermissionStatus
+	onerror onmessage onmessageerror onunhandledrejection onrejectionhandled
+`
+
+// 浏览器主线程特有的全局名
+const browserGlobalNames = `
+	window document frames parent top opener closed length name status history
+	screen localStorage sessionStorage alert confirm prompt print open close focus
+	blur stop postMessage requestAnimationFrame cancelAnimationFrame
+	requestIdleCallback cancelIdleCallback getComputedStyle getSelection matchMedia
+	scroll scrollBy scrollTo scrollX scrollY pageXOffset pageYOffset innerWidth
+	innerHeight outerWidth outerHeight screenX screenY screenLeft screenTop
+	devicePixelRatio visualViewport customElements event external frameElement
+	locationbar menubar personalbar scrollbars statusbar toolbar speechSynthesis
+	clientInformation trustedTypes
+	onload onunload onbeforeunload onresize onscroll onhashchange onpopstate
+	onclick ondblclick onkeydown onkeyup onkeypress onmousedown onmouseup
+	onmousemove onmouseover onmouseout onfocus onblur onchange oninput onsubmit
+	Window Document HTMLDocument XMLDocument DocumentFragment DocumentType Node
+	Element Text Comment CDATASection CharacterData ProcessingInstruction Attr
+	NodeList HTMLCollection NamedNodeMap DOMTokenList DOMParser XMLSerializer
+	XPathResult XPathEvaluator XPathExpression Range StaticRange Selection
+	TreeWalker NodeIterator NodeFilter MutationObserver MutationRecord
+	ResizeObserver ResizeObserverEntry IntersectionObserver IntersectionObserverEntry
+	ShadowRoot Location History Screen Storage StorageEvent CSS CSSStyleDeclaration
+	CSSStyleSheet CSSRule CSSRuleList StyleSheet StyleSheetList MediaQueryList
+	MediaQueryListEvent Image Audio Option HTMLElement HTMLAnchorElement
+	HTMLAreaElement HTMLAudioElement HTMLBaseElement HTMLBodyElement HTMLBRElement
+	HTMLButtonElement HTMLCanvasElement HTMLDataListElement HTMLDetailsElement
+	HTMLDialogElement HTMLDivElement HTMLDListElement HTMLEmbedElement
+	HTMLFieldSetElement HTMLFormElement HTMLFrameSetElement HTMLHeadElement
+	HTMLHeadingElement HTMLHRElement HTMLHtmlElement HTMLIFrameElement
+	HTMLImageElement HTMLInputElement HTMLLabelElement HTMLLegendElement
+	HTMLLIElement HTMLLinkElement HTMLMapElement HTMLMediaElement HTMLMetaElement
+	HTMLMeterElement HTMLObjectElement HTMLOListElement HTMLOptGroupElement
+	HTMLOptionElement HTMLOutputElement HTMLParagraphElement HTMLPictureElement
+	HTMLPreElement HTMLProgressElement HTMLQuoteElement HTMLScriptElement
+	HTMLSelectElement HTMLSlotElement HTMLSourceElement HTMLSpanElement
+	HTMLStyleElement HTMLTableCaptionElement HTMLTableCellElement
+	HTMLTableColElement HTMLTableElement HTMLTableRowElement
+	HTMLTableSectionElement HTMLTemplateElement HTMLTextAreaElement
+	HTMLTimeElement HTMLTitleElement HTMLTrackElement HTMLUListElement
+	HTMLUnknownElement HTMLVideoElement SVGElement SVGSVGElement
+	SVGGraphicsElement SVGPathElement SVGGElement SVGRectElement SVGCircleElement
+	SVGTextElement SVGUseElement
+	UIEvent MouseEvent KeyboardEvent FocusEvent InputEvent WheelEvent PointerEvent
+	TouchEvent Touch TouchList DragEvent ClipboardEvent CompositionEvent
+	AnimationEvent TransitionEvent HashChangeEvent PopStateEvent
+	PageTransitionEvent BeforeUnloadEvent SubmitEvent FormDataEvent
+	DataTransfer DataTransferItem DataTransferItemList Clipboard ClipboardItem
+	Geolocation GeolocationPosition GeolocationPositionError MediaDevices
+	MediaStream MediaStreamTrack MediaRecorder MediaSource SourceBuffer
+	RTCPeerConnection RTCSessionDescription RTCIceCandidate RTCDataChannel
+	AudioContext OfflineAudioContext AudioBuffer AudioNode AudioParam GainNode
+	OscillatorNode AnalyserNode BiquadFilterNode MediaElementAudioSourceNode
+	SpeechSynthesisUtterance IdleDeadline VisualViewport CanvasRenderingContext2D
+	ImageBitmapRenderingContext Plugin PluginArray MimeType MimeTypeArray
+	ServiceWorkerContainer SharedWorker
+`
+
+// Worker 共有的全局名
+const workerGlobalNames = `
+	WorkerGlobalScope WorkerNavigator WorkerLocation importScripts
+`
+
+// 专用 Worker 特有的全局名
+const webWorkerGlobalNames = `
+	DedicatedWorkerGlobalScope postMessage close name
+	requestAnimationFrame cancelAnimationFrame
+`
+
+// Service Worker 特有的全局名
+const serviceWorkerGlobalNames = `
+	ServiceWorkerGlobalScope clients registration serviceWorker skipWaiting
+	Clients Client WindowClient ExtendableEvent ExtendableMessageEvent FetchEvent
+	InstallEvent PushEvent PushMessageData SyncEvent NotificationEvent
+	oninstall onactivate onfetch onpush onsync onnotificationclick
+	onnotificationclose onpushsubscriptionchange
+`
+
+// Node.js 特有的全局名
+const nodeGlobalNames = `
+	global GLOBAL root process Buffer require module exports __dirname __filename
+	setImmediate clearImmediate
+`
+
+// 各目标环境的全局名，由上面的名称表组合而成
+var targetGlobalNames = map[string][]string{
+	TargetBrowser:       {esGlobalNames, hostGlobalNames, webGlobalNames, browserGlobalNames},
+	TargetWebWorker:     {esGlobalNames, hostGlobalNames, webGlobalNames, workerGlobalNames, webWorkerGlobalNames},
+	TargetServiceWorker: {esGlobalNames, hostGlobalNames, webGlobalNames, workerGlobalNames, serviceWorkerGlobalNames},
+	TargetNode:          {esGlobalNames, hostGlobalNames, nodeGlobalNames},
+	TargetNeutral:       {esGlobalNames},
+}
+
+// 加载目标环境的全局名集合，未指定时按浏览器处理
+func targetGlobals(target string) (map[string]bool, error) {
+	if target == "" {
+		target = TargetBrowser
+	}
+	lists, ok := targetGlobalNames[target]
+	if !ok {
+		return nil, errors.New("未知的目标环境: " + target)
+	}
+	globals := make(map[string]bool)
+	for _, list := range lists {
+		for _, name := range strings.Fields(list) {
+			globals[name] = true
+		}
+	}
+	return globals, nil
+}
@@ -0,0 +1,72 @@
+package obfuscator
+
+import (
+	"strings"
+	"testing"
+)
+
+// 顶层声明与目标环境的全局名同名时保持原名，其他环境中照常改名
+func TestTargetGlobals(t *testing.T) {
+	src := strings.Join([]string{
+		"function require(id) { return id; }",
+		"var process = { env: {} };",
+		"var self = this;",
+		"function importScripts() {}",
+		"var window = self;",
+		"var setImmediate = 1;",
+		"var local = require('x') + process + self + importScripts + window + setImmediate;",
+	}, "\n")
+	names := []string{"require", "process", "self", "importScripts", "window", "setImmediate"}
+	cases := []struct {
+		target string
+		kept   []string
+	}{
+		{"", []string{"self", "window"}},
+		{TargetBrowser, []string{"self", "window"}},
+		{TargetNode, []string{"require", "process", "setImmediate"}},
+		{TargetWebWorker, []string{"self", "importScripts"}},
+		{TargetServiceWorker, []string{"self", "importScripts"}},
+		{TargetNeutral, nil},
+	}
+	for _, tc := range cases {
+		result, err := Obfuscate(src, Config{IdentifierObfuscation: true, Target: tc.target, Seed: 1})
+		if err != nil {
+			t.Fatalf("target %q: %v", tc.target, err)
+		}
+		renamed := make(map[string]bool)
+		for _, entry := range result.RenameMap {
+			renamed[entry.Original] = true
+		}
+		if !renamed["local"] {
+			t.Errorf("target %q: 普通变量没有改名", tc.target)
+		}
+		kept := make(map[string]bool)
+		for _, name := range tc.kept {
+			kept[name] = true
+		}
+		for _, name := range names {
+			if renamed[name] == kept[name] {
+				t.Errorf("target %q: %s 改名为 %v，期望 %v:\n%s", tc.target, name, renamed[name], !kept[name], result.Code)
+			}
+		}
+	}
+
+	if _, err := Obfuscate(src, Config{Target: "deno"}); err == nil || !strings.Contains(err.Error(), "未知的目标环境") {
+		t.Errorf("未知的目标环境没有报错: %v", err)
+	}
+}
+
+// Node.js 目标中 require、process 等宿主提供的名称保持可用
+func TestTargetNodeEquivalence(t *testing.T) {
+	node := requireNode(t)
+	src := strings.Join([]string{
+		"var path = require('path');",
+		"var process = global.process;",
+		"function join(a, b) { return path.join(a, b); }",
+		"console.log(join('a', 'b'), typeof process.version, typeof setImmediate, typeof module, typeof exports);",
+	}, "\n")
+	configs := map[string]Config{
+		"node": {IdentifierObfuscation: true, Target: TargetNode},
+	}
+	checkEquivalent(t, node, src, configs, 3)
+}
@@ -0,0 +1,184 @@
+package obfuscator
+
+import (
+	"errors"
+	"math/rand"
+	"regexp"
+)
+
+// JavaScript 保留字和特殊名称，在任何作用域中都不参与改名
+//
+// 内置全局名随目标环境变化，见 globals.go。
+var reservedIdentifiers = map[string]bool{
+	// 关键字
+	"var": true, "let": true, "const": true, "function": true,
+	"if": true, "else": true, "for": true, "while": true, "do": true,
+	"switch": true, "case": true, "default": true, "break": true, "continue": true,
+	"return": true, "try": true, "catch": true, "finally": true, "throw": true,
+	"new": true, "this": true, "typeof": true, "instanceof": true, "in": true,
+	"void": true, "delete": true, "with": true, "debugger": true,
+	"class": true, "extends": true, "super": true, "static": true,
+	"import": true, "export": true, "from": true, "as": true,
+	"async": true, "await": true, "yield": true,
+	"enum": true, "implements": true, "interface": true, "package": true,
+	"private": true, "protected": true, "public": true,
+
+	// 字面量
+	"undefined": true, "null": true, "true": true, "false": true,
+
+	// 特殊名称
+	"arguments": true, "eval": true,
+}
+
+// 用户通过 reservedNames 和 reservedNamePatterns 指定的保留名称
+type reservedNames struct {
+	names    map[string]bool
+	patterns []*regexp.Regexp
+}
+
+func newReservedNames(cfg *Config) (*reservedNames, error) {
+	r := &reservedNames{names: make(map[string]bool)}
+	for _, name := range cfg.ReservedNames {
+		r.names[name] = true
+	}
+	for _, pattern := range cfg.ReservedNamePatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.New("无效的保留名称模式: " + pattern)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+func (r *reservedNames) match(name string) bool {
+	if r == nil {
+		return false
+	}
+	if r.names[name] {
+		return true
+	}
+	for _, re := range r.patterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func newIdentifierTransform() Transform {
+	return &builtinTransform{
+		name:        "identifiers",
+		description: "将用户定义的变量名、函数名和参数名替换为随机短名称",
+		options: []Option{
+			{Name: "identifierObfuscation", Type: "boolean", Description: "启用标识符混淆"},
+			{Name: "reservedNames", Type: "string[]", Description: "保持原名的标识符"},
+			{Name: "reservedNamePatterns", Type: "string[]", Description: "保持原名的标识符正则表达式"},
+			{Name: "target", Type: "string", Description: "目标运行环境，决定保持原名的内置全局名"},
+		},
+		enabled: func(cfg *Config) bool { return cfg.IdentifierObfuscation },
+		apply: func(ctx *Context) error {
+			ctx.RenameMap, ctx.KeptNames = obfuscateIdentifiers(ctx)
+			return nil
+		},
+	}
+}
+
+// 标识符混淆 - 只混淆用户定义的变量和函数名
+//
+// 基于作用域分析逐个绑定改名：同名但互不相关的变量得到不同的新名称，
+// 每个引用都跟随它实际解析到的绑定，对象属性名和标签不受影响。
+// 顶层声明与目标环境的全局名同名时保持原名，避免覆盖或断开宿主提供的对象。
+// 返回每个被改名绑定的原名、新名称、作用域和声明位置，以及因用户保留而未改名的声明。
+func obfuscateIdentifiers(ctx *Context) ([]RenameEntry, []string) {
+	program, rng, reserved := ctx.Program, ctx.Rand, ctx.reserved
+	tree := analyzeScopes(program)
+
+	// 保持原名的名称：未声明的全局名、保留字以及无法安全改名的绑定
+	taken := make(map[string]bool)
+	for name := range tree.free {
+		taken[name] = true
+	}
+	var targets []*binding
+	var kept []string
+	keptSeen := make(map[string]bool)
+	for _, b := range tree.bindings {
+		if b.frozen || reservedIdentifiers[b.name] || (b.scope.kind == scopeGlobal && ctx.IsGlobal(b.name)) {
+			taken[b.name] = true
+			continue
+		}
+		if reserved.match(b.name) {
+			taken[b.name] = true
+			if !keptSeen[b.name] {
+				keptSeen[b.name] = true
+				kept = append(kept, b.name)
+			}
+			continue
+		}
+		targets = append(targets, b)
+	}
+
+	// 按声明顺序为每个绑定生成全局唯一的新名称，保证输出稳定
+	// 作用域描述引用函数原名，因此全部记录完成后再统一改名
+	var entries []RenameEntry
+	renamed := make([]string, len(targets))
+	counter := 0
+	for i, b := range targets {
+		counter++
+		obfuscated := generateObfuscatedName(rng, counter)
+		for taken[obfuscated] || ctx.IsReserved(obfuscated) || ctx.IsGlobal(obfuscated) {
+			counter++
+			obfuscated = generateObfuscatedName(rng, counter)
+		}
+		taken[obfuscated] = true
+		renamed[i] = obfuscated
+
+		// 之前的变换插入的运行时代码不在源码中，只改名不记录；位置按原始源码（模板字符串降级前）计算
+		position := ctx.templates.position(b.idx)
+		if position == nil {
+			continue
+		}
+		entries = append(entries, RenameEntry{
+			Original:   b.name,
+			Obfuscated: obfuscated,
+			Kind:       b.kind.String(),
+			Scope:      b.scope.describe(),
+			Line:       position.Line,
+			Column:     position.Column,
+		})
+	}
+	for i, b := range targets {
+		b.rename(renamed[i])
+	}
+	return entries, kept
+}
+
+// 生成混淆后的标识符名称
+func generateObfuscatedName(rng *rand.Rand, counter int) string {
+	// 简化的混淆策略，兼容 TinyGo
+	switch rng.Intn(3) {
+	case 0:
+		return "_" + intToString(counter)
+	case 1:
+		return "$_" + intToString(counter)
+	default:
+		return generateRandomName(rng, 6)
+	}
+}
+
+// 生成随机名称
+func generateRandomName(rng *rand.Rand, length int) string {
+	chars := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ_$"
+	result := make([]byte, length)
+
+	// 第一个字符不能是数字
+	result[0] = chars[rng.Intn(len(chars))]
+
+	// 后续字符可以包含数字
+	allChars := chars + "0123456789"
+	for i := 1; i < length; i++ {
+		result[i] = allChars[rng.Intn(len(allChars))]
+	}
+
+	return string(result)
+}
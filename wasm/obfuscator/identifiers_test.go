@@ -0,0 +1,153 @@
+package obfuscator
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// 标识符改名前后的运行结果相同：遮蔽、提升、catch 参数、具名函数表达式、with 和 eval 都按原来的作用域解析
+func TestIdentifierScopingEquivalence(t *testing.T) {
+	node := requireNode(t)
+	src := strings.Join([]string{
+		"var log = [];",
+		"var value = 'global';",
+		// 遮蔽与闭包
+		"function shadow(value) {",
+		"  function inner() { var value = 'inner'; return value; }",
+		"  return [value, inner(), (function () { return value; })()].join('/');",
+		"}",
+		"log.push(shadow('param'), value);",
+		// var 与函数声明的提升
+		"function hoisting() {",
+		"  var before = typeof later + ':' + typeof fn + ':' + fn();",
+		"  if (true) { var later = 1; }",
+		"  function fn() { return 'fn'; }",
+		"  return before + ':' + later;",
+		"}",
+		"log.push(hoisting());",
+		// catch 参数只在 catch 块中可见，其中的 var 属于外层函数
+		"function caught() {",
+		"  var e = 'outer';",
+		"  try { throw 'thrown'; } catch (e) { var e = 'assigned', inside = e; }",
+		"  return [e, inside].join('/');",
+		"}",
+		"log.push(caught());",
+		// 具名函数表达式的名称只在函数内部可见
+		"var fact = function self(n) { return n <= 1 ? 1 : n * self(n - 1); };",
+		"log.push(fact(5), typeof self);",
+		// 循环中的闭包共享同一个 var
+		"function closures() {",
+		"  var fns = [];",
+		"  for (var i = 0; i < 3; i++) fns.push(function () { return i; });",
+		"  for (var j = 0; j < 3; j++) fns.push((function (j) { return function () { return j; }; })(j));",
+		"  return fns.map(function (f) { return f(); }).join('');",
+		"}",
+		"log.push(closures());",
+		// with 与直接 eval 中按名称访问的变量保持原名
+		"function dynamic() {",
+		"  var local = 'local', obj = { prop: 'prop' };",
+		"  with (obj) { var viaWith = prop + local; }",
+		"  var viaEval = eval('local + viaWith');",
+		"  return viaEval;",
+		"}",
+		"log.push(dynamic());",
+		// 属性名、标签与未声明的全局名不受影响
+		"function names() {",
+		"  var o = { value: 1, log: 2 };",
+		"  value: for (var k in o) { if (k === 'log') break value; log.push(k + o[k]); }",
+		"  return typeof undeclaredGlobal + ':' + typeof JSON.stringify;",
+		"}",
+		"log.push(names());",
+		"console.log(log.join());",
+	}, "\n")
+	configs := map[string]Config{
+		"identifiers": {IdentifierObfuscation: true},
+		"reserved":    {IdentifierObfuscation: true, ReservedNames: []string{"value"}, ReservedNamePatterns: []string{"^c"}},
+		"combined":    {IdentifierObfuscation: true, ControlFlowFlattening: true, ControlFlowFlatteningThreshold: Threshold(1), ExpressionDecomposition: true},
+	}
+	checkEquivalent(t, node, src, configs, 5)
+}
+
+// 改名映射按声明顺序记录每个绑定的种类、作用域和在源码中的位置
+func TestRenameMapEntries(t *testing.T) {
+	src := strings.Join([]string{
+		"var total = 0;",
+		"function outer(a, b) {",
+		"  var sum = a + b;",
+		"  var inner = function () { var x = sum; return x; };",
+		"  try { throw sum; } catch (e) { total = e; }",
+		"  return inner();",
+		"}",
+		"var named = function self() { return self; };",
+	}, "\n")
+	result, err := Obfuscate(src, Config{IdentifierObfuscation: true, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []RenameEntry{
+		{Original: "total", Kind: "var", Scope: "global", Line: 1, Column: 5},
+		{Original: "outer", Kind: "function", Scope: "global", Line: 2, Column: 10},
+		{Original: "named", Kind: "var", Scope: "global", Line: 8, Column: 5},
+		{Original: "a", Kind: "parameter", Scope: "outer", Line: 2, Column: 16},
+		{Original: "b", Kind: "parameter", Scope: "outer", Line: 2, Column: 19},
+		{Original: "sum", Kind: "var", Scope: "outer", Line: 3, Column: 7},
+		{Original: "inner", Kind: "var", Scope: "outer", Line: 4, Column: 7},
+		{Original: "x", Kind: "var", Scope: "outer/(anonymous)", Line: 4, Column: 33},
+		{Original: "e", Kind: "catch", Scope: "outer/catch", Line: 5, Column: 29},
+		{Original: "self", Kind: "functionName", Scope: "global", Line: 8, Column: 22},
+	}
+	if len(result.RenameMap) != len(want) {
+		t.Fatalf("改名映射有 %d 条，期望 %d 条: %+v", len(result.RenameMap), len(want), result.RenameMap)
+	}
+	for i, entry := range result.RenameMap {
+		if entry.Obfuscated == "" || entry.Obfuscated == entry.Original {
+			t.Errorf("%s 的新名称为 %q", entry.Original, entry.Obfuscated)
+		}
+		entry.Obfuscated = ""
+		if entry != want[i] {
+			t.Errorf("第 %d 条为 %+v，期望 %+v", i, entry, want[i])
+		}
+	}
+}
+
+// reservedNames 和 reservedNamePatterns 匹配的声明保持原名，按首次声明的顺序记入 KeptNames
+func TestReservedNamesKept(t *testing.T) {
+	src := strings.Join([]string{
+		"function initWidget(options) { var apiKey = options.key; var helper = apiKey + 1; return helper; }",
+		"function aa() { var a = 1, b = 2, initWidget = a + b; return initWidget; }",
+		"var api_url = 'x', renamed = initWidget({ key: 1 }) + aa();",
+	}, "\n")
+	result, err := Obfuscate(src, Config{
+		IdentifierObfuscation: true,
+		ReservedNames:         []string{"initWidget", "notDeclared"},
+		ReservedNamePatterns:  []string{"^a{1,2}$", "^api"},
+		Seed:                  1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"initWidget", "aa", "api_url", "apiKey", "a"}
+	if strings.Join(result.KeptNames, ",") != strings.Join(want, ",") {
+		t.Errorf("KeptNames = %v，期望 %v", result.KeptNames, want)
+	}
+	for _, name := range want {
+		if !regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\b`).MatchString(result.Code) {
+			t.Errorf("%s 被改名:\n%s", name, result.Code)
+		}
+	}
+	renamed := make(map[string]bool)
+	for _, entry := range result.RenameMap {
+		renamed[entry.Original] = true
+	}
+	for _, name := range want {
+		if renamed[name] {
+			t.Errorf("保留名称 %s 出现在改名映射中", name)
+		}
+	}
+	for _, name := range []string{"options", "helper", "b", "renamed"} {
+		if !renamed[name] {
+			t.Errorf("%s 没有改名:\n%s", name, result.Code)
+		}
+	}
+}
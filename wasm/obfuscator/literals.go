@@ -0,0 +1,155 @@
+package obfuscator
+
+import (
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/token"
+)
+
+func newLiteralsTransform() Transform {
+	return &builtinTransform{
+		name:        "literals",
+		description: "将 true、false、null、undefined 改写为等值表达式或常量变量",
+		options: []Option{
+			{Name: "disguiseLiterals", Type: "boolean", Description: "启用字面量伪装"},
+		},
+		enabled: func(cfg *Config) bool { return cfg.DisguiseLiterals },
+		apply: func(ctx *Context) error {
+			disguiseLiterals(ctx)
+			return nil
+		},
+	}
+}
+
+// 常量变量的种类
+const (
+	holderTrue = iota
+	holderFalse
+	holderNull
+	holderUndefined
+	holderCount
+)
+
+// 字面量伪装
+//
+// true、false 改写为 !![]、!0、![]、!1，undefined 改写为 void 0，
+// 或随机改为引用程序开头声明的常量变量；null 没有等值的表达式，总是使用常量变量。
+// 只处理表达式位置的节点，属性名、关键字等不会被访问。
+// 解析到局部声明或位于 with 语句中的 undefined 不一定是全局值，保持原样；
+// 赋值目标、delete 和自增自减的操作数也保持原样。
+func disguiseLiterals(ctx *Context) {
+	// 解析到局部绑定的 undefined
+	local := make(map[*string]bool)
+	for _, b := range analyzeScopes(ctx.Program).bindings {
+		if b.name == "undefined" {
+			for _, site := range b.sites {
+				local[site] = true
+			}
+		}
+	}
+	var holders [holderCount]string
+	holder := func(kind int) ast.Expression {
+		if holders[kind] == "" {
+			holders[kind] = ctx.newName()
+		}
+		return &ast.Identifier{Name: holders[kind]}
+	}
+
+	skip := make(map[ast.Expression]bool)
+	withDepth := 0
+	walker := &astWalker{
+		enter: func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.WithStatement:
+				withDepth++
+			case *ast.AssignExpression:
+				skip[n.Left] = true
+			case *ast.ForInStatement:
+				skip[n.Into] = true
+			case *ast.UnaryExpression:
+				switch n.Operator {
+				case token.DELETE, token.INCREMENT, token.DECREMENT:
+					skip[n.Operand] = true
+				}
+			}
+			return true
+		},
+		leave: func(node ast.Node) ast.Node {
+			var expr ast.Expression
+			switch n := node.(type) {
+			case *ast.WithStatement:
+				withDepth--
+				return node
+			case *ast.BooleanLiteral:
+				kind := holderFalse
+				if n.Value {
+					kind = holderTrue
+				}
+				if ctx.Rand.Intn(2) == 0 {
+					expr = holder(kind)
+				} else {
+					expr = booleanExpression(ctx, n.Value)
+				}
+				inheritPosition(expr, n.Idx)
+			case *ast.NullLiteral:
+				expr = holder(holderNull)
+				inheritPosition(expr, n.Idx)
+			case *ast.Identifier:
+				if n.Name != "undefined" || local[&n.Name] || withDepth > 0 || skip[n] {
+					return node
+				}
+				if ctx.Rand.Intn(2) == 0 {
+					expr = holder(holderUndefined)
+				} else {
+					expr = &ast.UnaryExpression{Operator: token.VOID, Operand: numberLiteral(0)}
+				}
+				inheritPosition(expr, n.Idx)
+			default:
+				return node
+			}
+			return expr
+		},
+	}
+	walker.walk(ctx.Program)
+
+	// 常量变量按随机顺序声明在程序开头，早于任何引用
+	var list []ast.Expression
+	for _, kind := range ctx.Rand.Perm(holderCount) {
+		if holders[kind] == "" {
+			continue
+		}
+		var value ast.Expression
+		switch kind {
+		case holderTrue:
+			value = booleanExpression(ctx, true)
+		case holderFalse:
+			value = booleanExpression(ctx, false)
+		case holderNull:
+			value = &ast.NullLiteral{Literal: "null"}
+		case holderUndefined:
+			value = &ast.UnaryExpression{Operator: token.VOID, Operand: numberLiteral(0)}
+		}
+		list = append(list, &ast.VariableExpression{Name: holders[kind], Initializer: value})
+	}
+	if len(list) > 0 {
+		prependStatements(ctx.Program, &ast.VariableStatement{List: list})
+	}
+}
+
+// 取值为 value 的布尔表达式
+func booleanExpression(ctx *Context, value bool) ast.Expression {
+	var operand ast.Expression
+	if ctx.Rand.Intn(2) == 0 {
+		// ![] 为 false
+		operand = &ast.ArrayLiteral{}
+		if value {
+			operand = &ast.UnaryExpression{Operator: token.NOT, Operand: operand}
+		}
+	} else {
+		// !0 为 true，!1 为 false
+		operand = numberLiteral(1)
+		if value {
+			operand = numberLiteral(0)
+		}
+	}
+	return &ast.UnaryExpression{Operator: token.NOT, Operand: operand}
+}
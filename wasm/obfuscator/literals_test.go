@@ -0,0 +1,33 @@
+package obfuscator
+
+import (
+	"strings"
+	"testing"
+)
+
+// 字面量伪装前后的运行结果相同：局部声明的 undefined 保持原样，作为属性名的 true、null 不受影响
+func TestLiteralsEquivalence(t *testing.T) {
+	node := requireNode(t)
+	src := strings.Join([]string{
+		"var log = [];",
+		"log.push(undefined, typeof undefined, true, false, null, !true, null === undefined);",
+		// 局部声明遮蔽全局的 undefined
+		"function param(undefined) { return [undefined, typeof undefined].join(); }",
+		"function local() { var undefined = 'local'; return undefined + (function () { return undefined; })(); }",
+		"function caught() { try { throw 'caught'; } catch (undefined) { return undefined; } }",
+		"function named() { function undefined() { return 'fn'; } return undefined(); }",
+		"log.push(param(5), param(), local(), caught(), named(), undefined);",
+		"with ({ undefined: 'with' }) { log.push(undefined); }",
+		// 作为属性名与对象字面量的键
+		"var o = { true: 't', false: 'f', null: 'n', undefined: 'u' };",
+		"o.true += '!'; o.null = o.null + o.false;",
+		"log.push(o.true, o.false, o.null, o.undefined, o['true'], Object.keys(o).join('/'));",
+		"var p = { null: 1 }; log.push(p.null, 'null' in p, p[null], { true: true }.true);",
+		"console.log(log.join('|'));",
+	}, "\n")
+	configs := map[string]Config{
+		"literals": {DisguiseLiterals: true},
+		"combined": {DisguiseLiterals: true, IdentifierObfuscation: true, TransformMemberExpressions: true, TransformObjectKeys: true},
+	}
+	checkEquivalent(t, node, src, configs, 5)
+}
@@ -0,0 +1,49 @@
+package obfuscator
+
+import (
+	"github.com/robertkrimen/otto/ast"
+)
+
+func newMembersTransform() Transform {
+	return &builtinTransform{
+		name:        "members",
+		description: "将 a.b 形式的属性访问改写为 a['b']，属性名交给字符串变换处理",
+		options: []Option{
+			{Name: "transformMemberExpressions", Type: "boolean", Description: "启用属性访问改写"},
+			{Name: "memberExpressionsDenylist", Type: "string[]", Description: "保持 a.b 形式的属性名"},
+		},
+		enabled: func(cfg *Config) bool { return cfg.TransformMemberExpressions },
+		apply: func(ctx *Context) error {
+			transformMemberExpressions(ctx)
+			return nil
+		},
+	}
+}
+
+// 属性访问改写
+//
+// a.b 改写为 a['b']，两者语义完全相同，之后的字符串拆分、字符串数组和字符串加密
+// 会像处理其他字符串一样隐藏属性名。memberExpressionsDenylist 中的属性名保持原样。
+// 可选链 a?.b 属于 ES2020 语法，解析阶段就会报错，需要先转译。
+func transformMemberExpressions(ctx *Context) {
+	denied := make(map[string]bool, len(ctx.Config.MemberExpressionsDenylist))
+	for _, name := range ctx.Config.MemberExpressionsDenylist {
+		denied[name] = true
+	}
+	walker := &astWalker{
+		leave: func(node ast.Node) ast.Node {
+			dot, ok := node.(*ast.DotExpression)
+			if !ok || denied[dot.Identifier.Name] {
+				return node
+			}
+			member := &ast.StringLiteral{Idx: dot.Identifier.Idx, Value: dot.Identifier.Name}
+			return &ast.BracketExpression{
+				Left:         dot.Left,
+				Member:       member,
+				LeftBracket:  dot.Identifier.Idx,
+				RightBracket: dot.Identifier.Idx,
+			}
+		},
+	}
+	walker.walk(ctx.Program)
+}
@@ -0,0 +1,57 @@
+package obfuscator
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 单次运行的时限，混淆结果陷入死循环时测试失败而不是挂起
+const nodeTimeout = 10 * time.Second
+
+// node 的路径，没有 node 时跳过测试
+func requireNode(t *testing.T) string {
+	t.Helper()
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("未找到 node")
+	}
+	return node
+}
+
+// 用 node 以非严格模式的脚本运行代码，返回标准输出
+func runNode(t *testing.T, node, code string) string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), nodeTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, node)
+	cmd.Stdin = strings.NewReader(code)
+	output, err := cmd.Output()
+	if ctx.Err() != nil {
+		t.Fatalf("运行超时\n%s", code)
+	}
+	if err != nil {
+		t.Fatalf("运行失败: %v\n%s", err, code)
+	}
+	return string(output)
+}
+
+// 每个配置在 1 到 seeds 的种子下混淆 src，运行结果都应与原始代码相同
+func checkEquivalent(t *testing.T, node, src string, configs map[string]Config, seeds int64) {
+	t.Helper()
+	want := runNode(t, node, src)
+	for name, cfg := range configs {
+		for seed := int64(1); seed <= seeds; seed++ {
+			cfg.Seed = seed
+			result, err := Obfuscate(src, cfg)
+			if err != nil {
+				t.Fatalf("%s seed %d: %v", name, seed, err)
+			}
+			if got := runNode(t, node, result.Code); got != want {
+				t.Errorf("%s seed %d: 输出\n%s\n期望\n%s\n代码\n%s", name, seed, got, want, result.Code)
+			}
+		}
+	}
+}
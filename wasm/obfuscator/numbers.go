@@ -0,0 +1,167 @@
+package obfuscator
+
+import (
+	"math"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/token"
+)
+
+func newNumbersTransform() Transform {
+	return &builtinTransform{
+		name:        "numbers",
+		description: "将数字字面量改写为等值的算术或位运算表达式",
+		options: []Option{
+			{Name: "numbersToExpressions", Type: "boolean", Description: "启用数字转表达式"},
+			{Name: "numbersToExpressionsSkipCaseLabels", Type: "boolean", Description: "case 标签中的数字保持字面量"},
+		},
+		enabled: func(cfg *Config) bool { return cfg.NumbersToExpressions },
+		apply: func(ctx *Context) error {
+			numbersToExpressions(ctx)
+			return nil
+		},
+	}
+}
+
+// 整数运算保持精确的上限 2^53
+const maxSafeInteger = 1 << 53
+
+// 数字转表达式
+//
+// 每个数字字面量改写为一个结果完全相同的表达式，如 8080 变为 1047866 ^ 1041066。
+// 构造时用 float64 按 IEEE-754 双精度（与 JavaScript 相同）计算验证，
+// 结果不精确时换一种构造，都不精确时保持原样。
+// 在平坦化之后执行，状态机中的状态值也会被改写；
+// numbersToExpressionsSkipCaseLabels 打开时 case 标签保持字面量。
+func numbersToExpressions(ctx *Context) {
+	skip := make(map[*ast.NumberLiteral]bool)
+	// 自我保护的期望值在生成代码后原位填入
+	if ctx.selfDefending != nil && ctx.selfDefending.expected != nil {
+		skip[ctx.selfDefending.expected] = true
+	}
+	walker := &astWalker{
+		enter: func(node ast.Node) bool {
+			if c, ok := node.(*ast.CaseStatement); ok && ctx.Config.NumbersToExpressionsSkipCaseLabels {
+				test := c.Test
+				if unary, ok := test.(*ast.UnaryExpression); ok && unary.Operator == token.MINUS {
+					test = unary.Operand
+				}
+				if literal, ok := test.(*ast.NumberLiteral); ok {
+					skip[literal] = true
+				}
+			}
+			return true
+		},
+		leave: func(node ast.Node) ast.Node {
+			literal, ok := node.(*ast.NumberLiteral)
+			if !ok || skip[literal] {
+				return node
+			}
+			value, ok := numberValue(literal)
+			if !ok {
+				return node
+			}
+			if expr := numberExpression(ctx, value); expr != nil {
+				inheritPosition(expr, literal.Idx)
+				return expr
+			}
+			return node
+		},
+	}
+	walker.walk(ctx.Program)
+}
+
+// 数字字面量的值
+func numberValue(literal *ast.NumberLiteral) (float64, bool) {
+	switch v := literal.Value.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, !math.IsNaN(v) && !math.IsInf(v, 0)
+	}
+	return 0, false
+}
+
+// 构造与 value 精确相等的表达式，失败时返回 nil
+func numberExpression(ctx *Context, value float64) ast.Expression {
+	rng := ctx.Rand
+	integer := value == math.Trunc(value) && math.Abs(value) < maxSafeInteger
+	for attempt := 0; attempt < 4; attempt++ {
+		var left, right float64
+		var op token.Token
+		if integer {
+			switch rng.Intn(4) {
+			case 0:
+				// a + b
+				left = float64(rng.Intn(1 << 20))
+				op, right = token.PLUS, value-left
+			case 1:
+				// a - b
+				right = float64(1 + rng.Intn(1<<20))
+				op, left = token.MINUS, value+right
+			case 2:
+				// a * b + c，乘积单独构成左侧
+				m := float64(2 + rng.Intn(30))
+				q := math.Floor(value / m)
+				if q == 0 {
+					continue
+				}
+				product := &ast.BinaryExpression{Operator: token.MULTIPLY, Left: floatLiteral(m), Right: floatLiteral(q)}
+				if r := value - m*q; r >= 0 && m*q+r == value {
+					return &ast.BinaryExpression{Operator: token.PLUS, Left: product, Right: floatLiteral(r)}
+				}
+				continue
+			case 3:
+				// a ^ b，只用于 32 位有符号整数范围内的非负数
+				if value < 0 || value > math.MaxInt32 {
+					continue
+				}
+				key := rng.Int31()
+				op, left, right = token.EXCLUSIVE_OR, float64(int32(value)^key), float64(key)
+			}
+		} else {
+			switch rng.Intn(3) {
+			case 0:
+				left = float64(1 + rng.Intn(1000))
+				op, right = token.PLUS, value-left
+			case 1:
+				right = float64(2 + rng.Intn(15))
+				op, left = token.MULTIPLY, value/right
+			case 2:
+				right = float64(2 + rng.Intn(15))
+				op, left = token.SLASH, value*right
+			}
+		}
+		if exactBinary(op, left, right, value) {
+			return &ast.BinaryExpression{Operator: op, Left: floatLiteral(left), Right: floatLiteral(right)}
+		}
+	}
+	return nil
+}
+
+// 按双精度计算验证结果，操作数本身也必须能被字面量精确表示
+func exactBinary(op token.Token, left, right, value float64) bool {
+	if math.IsInf(left, 0) || math.IsInf(right, 0) || math.IsNaN(left) || math.IsNaN(right) {
+		return false
+	}
+	var result float64
+	switch op {
+	case token.PLUS:
+		result = left + right
+	case token.MINUS:
+		result = left - right
+	case token.MULTIPLY:
+		result = left * right
+	case token.SLASH:
+		result = left / right
+	case token.EXCLUSIVE_OR:
+		result = float64(int32(left) ^ int32(right))
+	}
+	// 不接受 -0：字面量不会是 -0，而结果为 0 时符号必须一致
+	return result == value && math.Signbit(result) == math.Signbit(value)
+}
+
+// 浮点数字面量节点，负数由代码生成器输出为一元负号
+func floatLiteral(value float64) *ast.NumberLiteral {
+	return &ast.NumberLiteral{Value: value}
+}
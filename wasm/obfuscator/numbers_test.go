@@ -0,0 +1,64 @@
+package obfuscator
+
+import (
+	"strings"
+	"testing"
+)
+
+// 数字转表达式前后的值完全相同，包括 -0、不精确的小数、超出安全整数范围和极大极小的浮点数
+func TestNumbersEquivalence(t *testing.T) {
+	node := requireNode(t)
+	src := strings.Join([]string{
+		"var values = [-0, 0, 0.1, 0.1 + 0.2, 0.3, 123.456, 9007199254740993, 9007199254740992, 9007199254740991,",
+		"  -9007199254740993, 18014398509481985, 1e21, 1.7976931348623157e308, 1e300, -1e300, 5e-324,",
+		"  2.2250738585072014e-308, 1e-7, 0x1f, 2147483647, -2147483648, 4294967295, NaN, Infinity, -Infinity, 1 / 0];",
+		"var out = [];",
+		"for (var i = 0; i < values.length; i++) {",
+		"  var v = values[i];",
+		"  out.push(String(v) + (v === 0 ? (1 / v > 0 ? '+' : '-') : '') + ':' + (v === v ? v.toString(16) : 'nan'));",
+		"}",
+		"console.log(out.join(' '));",
+	}, "\n")
+	configs := map[string]Config{
+		"numbers":     {NumbersToExpressions: true},
+		"controlFlow": {NumbersToExpressions: true, ControlFlowFlattening: true, ExpressionDecomposition: true},
+	}
+	checkEquivalent(t, node, src, configs, 10)
+}
+
+// numbersToExpressionsSkipCaseLabels 打开时 case 标签保持字面量，其他数字照常改写
+func TestNumbersSkipCaseLabels(t *testing.T) {
+	node := requireNode(t)
+	src := strings.Join([]string{
+		"function label(x) {",
+		"  switch (x) {",
+		"    case 1: return 'one';",
+		"    case -2: return 'minus two';",
+		"    case 3.5: return 'three and a half';",
+		"    case 1000: return 'thousand';",
+		"  }",
+		"  return 'other';",
+		"}",
+		"console.log(label(1), label(-2), label(3.5), label(1000), label(7));",
+	}, "\n")
+	configs := map[string]Config{
+		"skip": {NumbersToExpressions: true, NumbersToExpressionsSkipCaseLabels: true},
+	}
+	checkEquivalent(t, node, src, configs, 3)
+
+	for seed := int64(1); seed <= 5; seed++ {
+		result, err := Obfuscate(src, Config{NumbersToExpressions: true, NumbersToExpressionsSkipCaseLabels: true, CompactCode: true, Seed: seed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, label := range []string{"case 1:", "case-2:", "case 3.5:", "case 1000:"} {
+			if !strings.Contains(result.Code, label) {
+				t.Errorf("seed %d: %s 没有保持字面量:\n%s", seed, label, result.Code)
+			}
+		}
+		// 调用参数中的数字仍然被改写
+		if strings.Contains(result.Code, "label(1000)") {
+			t.Errorf("seed %d: case 标签以外的数字没有改写:\n%s", seed, result.Code)
+		}
+	}
+}
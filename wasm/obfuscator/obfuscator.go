@@ -0,0 +1,255 @@
+// Package obfuscator 实现 JavaScript 代码混淆引擎。
+//
+// 引擎不依赖浏览器环境，既可以被 wasm 前端调用，也可以在普通 Go 程序中使用。
+package obfuscator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+	"github.com/robertkrimen/otto/parser"
+)
+
+// Config 混淆配置
+type Config struct {
+	IdentifierObfuscation   bool `json:"identifierObfuscation"`
+	StringEncryption        bool `json:"stringEncryption"`
+	ControlFlowFlattening   bool `json:"controlFlowFlattening"`
+	DeadCodeInjection       bool `json:"deadCodeInjection"`
+	ExpressionDecomposition bool `json:"expressionDecomposition"`
+	CompactCode             bool `json:"compactCode"`
+	PreserveComments        bool `json:"preserveComments"`
+	// ControlFlowFlatteningThreshold 控制流平坦化的函数比例（0~1，未设置时为 1，0 表示不平坦化）
+	ControlFlowFlatteningThreshold *float64 `json:"controlFlowFlatteningThreshold"`
+	// DeadCodeInjectionThreshold 注入死代码的语句比例（0~1，未设置时为 0.4，0 表示不注入）
+	DeadCodeInjectionThreshold *float64 `json:"deadCodeInjectionThreshold"`
+	// SplitStrings 将较长的字符串拆成若干段拼接，SplitStringsChunkLength 是每段的长度（默认 10）
+	SplitStrings            bool `json:"splitStrings"`
+	SplitStringsChunkLength int  `json:"splitStringsChunkLength"`
+	// NumbersToExpressions 将数字字面量改写为等值表达式，
+	// NumbersToExpressionsSkipCaseLabels 时 case 标签中的数字保持字面量
+	NumbersToExpressions               bool `json:"numbersToExpressions"`
+	NumbersToExpressionsSkipCaseLabels bool `json:"numbersToExpressionsSkipCaseLabels"`
+	// DisguiseLiterals 将 true、false、null、undefined 改写为等值表达式或常量变量
+	DisguiseLiterals bool `json:"disguiseLiterals"`
+	// TransformMemberExpressions 将 a.b 改写为 a['b']，MemberExpressionsDenylist 中的属性名保持原样；
+	// 可选链 a?.b 属于 ES2020 语法，不会被降级，解析时报错并提示先转译为 ES5
+	TransformMemberExpressions bool     `json:"transformMemberExpressions"`
+	MemberExpressionsDenylist  []string `json:"memberExpressionsDenylist"`
+	// TransformObjectKeys 将对象字面量的属性改为逐个赋值，属性名成为字符串
+	TransformObjectKeys bool `json:"transformObjectKeys"`
+	// MangleProperties 统一改名匹配 ManglePropertiesRegex 的属性名，
+	// PropertyMap 是之前构建导出的属性名映射（Result.PropertyMap），其中的属性沿用原来的新名称
+	MangleProperties      bool              `json:"mangleProperties"`
+	ManglePropertiesRegex string            `json:"manglePropertiesRegex"`
+	PropertyMap           map[string]string `json:"propertyMap"`
+	// SelfDefending 插入自我保护代码，输出被格式化或改动后无法正常运行；打开时总是压缩输出
+	SelfDefending bool `json:"selfDefending"`
+	// StringArray 将字符串移入数组，StringArrayThreshold 是移入的比例（0~1，未设置时为 1，0 表示不移入），
+	// StringArrayRotate 在运行时旋转数组，StringArrayIndexOffset 是访问下标的偏移量
+	StringArray            bool     `json:"stringArray"`
+	StringArrayThreshold   *float64 `json:"stringArrayThreshold"`
+	StringArrayRotate      bool     `json:"stringArrayRotate"`
+	StringArrayIndexOffset int      `json:"stringArrayIndexOffset"`
+	// StringCipher 字符串加密算法：rc4、xor，为空时只做编码
+	StringCipher string `json:"stringCipher"`
+	// ShuffleBase64Alphabet 每次构建随机打乱 Base64 字母表
+	ShuffleBase64Alphabet bool `json:"shuffleBase64Alphabet"`
+	// Target 目标运行环境：browser（默认）、node、webworker、service-worker 或 neutral，
+	// 决定哪些全局名由宿主提供、必须保持原样
+	Target string `json:"target"`
+	// ReservedNames 保持原名的标识符，ReservedNamePatterns 是同样用途的正则表达式
+	ReservedNames        []string `json:"reservedNames"`
+	ReservedNamePatterns []string `json:"reservedNamePatterns"`
+	// SourceMap 同时生成 Source Map v3，SourceFileName 是其中记录的源文件名
+	SourceMap      bool   `json:"sourceMap"`
+	SourceFileName string `json:"sourceFileName"`
+	// Seed 随机数种子，相同的输入、配置和种子产生完全相同的输出；为 0 时自动选取
+	Seed int64 `json:"seed"`
+}
+
+// Threshold 返回指向 v 的指针，用于设置 Config 中的比例配置项
+//
+// 比例配置项未设置（nil）时使用各自的默认值，0 表示不处理，1 表示全部处理。
+func Threshold(v float64) *float64 {
+	return &v
+}
+
+// 比例配置项的取值，未设置时使用默认值，超出 0~1 的部分截断
+func thresholdValue(value *float64, defaultValue float64) float64 {
+	if value == nil {
+		return defaultValue
+	}
+	return math.Max(0, math.Min(1, *value))
+}
+
+// Result 混淆结果
+type Result struct {
+	Code string `json:"code"`
+	// Seed 本次实际使用的随机数种子
+	Seed  int64 `json:"seed"`
+	Stats Stats `json:"stats"`
+	// SourceMap Source Map v3 JSON，只在 Config.SourceMap 打开时生成
+	SourceMap string `json:"sourceMap,omitempty"`
+	// RenameMap 标识符混淆中每个被改名的绑定，按声明顺序排列
+	RenameMap []RenameEntry `json:"renameMap,omitempty"`
+	// KeptNames 因 ReservedNames 或 ReservedNamePatterns 保持原名的声明
+	KeptNames []string `json:"keptNames,omitempty"`
+	// PropertyMap 属性名改名的完整映射（原名到新名称），保存后可作为下次构建的 Config.PropertyMap
+	PropertyMap map[string]string `json:"propertyMap,omitempty"`
+}
+
+// RenameEntry 一个绑定的改名记录
+type RenameEntry struct {
+	Original   string `json:"original"`
+	Obfuscated string `json:"obfuscated"`
+	// Kind 绑定类型：var、function、parameter、functionName 或 catch
+	Kind string `json:"kind"`
+	// Scope 声明所在的作用域，如 global 或 outer/(anonymous)
+	Scope string `json:"scope"`
+	// Line、Column 声明在源码中的位置，从 1 开始
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Stats 混淆前后的体积统计
+type Stats struct {
+	OriginalSize   int     `json:"originalSize"`
+	ObfuscatedSize int     `json:"obfuscatedSize"`
+	Compression    float64 `json:"compression"`
+}
+
+// Obfuscate 使用默认的变换注册表混淆 JavaScript 源码
+func Obfuscate(src string, cfg Config) (Result, error) {
+	return DefaultRegistry().Obfuscate(src, cfg)
+}
+
+// Obfuscate 使用注册表中的变换混淆源码
+func (r *Registry) Obfuscate(src string, cfg Config) (result Result, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			result, err = Result{}, fmt.Errorf("混淆过程出现内部错误: %v", rec)
+		}
+	}()
+
+	if cfg.Seed == 0 {
+		cfg.Seed = newSeed()
+	}
+	result, err = r.run(src, cfg)
+	if err != nil {
+		return Result{}, err
+	}
+
+	// 计算统计信息
+	result.Seed = cfg.Seed
+	result.Stats = Stats{
+		OriginalSize:   len(src),
+		ObfuscatedSize: len(result.Code),
+		Compression:    float64(len(result.Code)) / float64(len(src)),
+	}
+	return result, nil
+}
+
+// 执行实际的混淆操作：解析为 AST，依次执行各个变换，再生成代码
+func (r *Registry) run(code string, config Config) (Result, error) {
+	// 如果代码为空，直接返回
+	if strings.TrimSpace(code) == "" {
+		return Result{Code: code}, nil
+	}
+
+	mode := parser.IgnoreRegExpErrors
+	if config.PreserveComments {
+		mode |= parser.StoreComments
+	}
+	// 模板字符串先降级为 ES5，之后的变换和代码生成都基于降级后的代码
+	templates := lowerTemplates(code)
+	fileSet := &file.FileSet{}
+	program, err := parser.ParseFile(fileSet, "", templates.code, mode)
+	if err != nil {
+		return Result{}, errors.New("语法解析失败: " + strings.Join(parseErrorMessages(templates, err), "; "))
+	}
+	normalizeNumericKeys(program, templates.code)
+
+	reserved, err := newReservedNames(&config)
+	if err != nil {
+		return Result{}, err
+	}
+	globals, err := targetGlobals(config.Target)
+	if err != nil {
+		return Result{}, err
+	}
+	ctx := &Context{
+		Program:   program,
+		Config:    &config,
+		Source:    templates.code,
+		Rand:      rand.New(rand.NewSource(config.Seed)),
+		reserved:  reserved,
+		globals:   globals,
+		fileSet:   fileSet,
+		templates: templates,
+	}
+	prependStatements(program, templates.runtime(ctx)...)
+	if err := r.apply(ctx); err != nil {
+		return Result{}, err
+	}
+
+	// 代码生成：压缩模式下不输出多余的空白；注释只在保留时输出
+	// 自我保护的校验值对换行符转换敏感，打开时总是压缩
+	compact := config.CompactCode || config.SelfDefending
+	result := Result{RenameMap: ctx.RenameMap, KeptNames: ctx.KeptNames, PropertyMap: ctx.PropertyMap}
+	var sourceMap *sourceMapBuilder
+	if config.SourceMap {
+		sourceMap = newSourceMapBuilder(templates)
+	}
+	// 自我保护的期望校验值要按最终的代码计算，生成之后再填入
+	var spans map[ast.Node][2]int
+	if ctx.selfDefending != nil && ctx.selfDefending.expected == nil {
+		// 解码函数用到了检查结果，但注册表中的 selfDefending 变换没有执行
+		prependStatements(program, ctx.parseRuntime("var __RESULT__ = 0;", map[string]string{"__RESULT__": ctx.selfDefending.result})...)
+		ctx.selfDefending = nil
+	}
+	if ctx.selfDefending != nil {
+		spans = ctx.selfDefending.spans()
+	}
+	result.Code = generateCodeSpans(program, templates.code, compact, sourceMap, spans)
+	if ctx.selfDefending != nil {
+		if result.Code, err = ctx.selfDefending.seal(result.Code, spans); err != nil {
+			return Result{}, err
+		}
+	}
+	if sourceMap != nil {
+		result.SourceMap = sourceMap.json(config.SourceFileName)
+	}
+	return result, nil
+}
+
+// 自动选取随机数种子
+//
+// 种子限制在 2^53 以内，经过 JavaScript 的 Number 往返后仍然精确。
+func newSeed() int64 {
+	seed := time.Now().UnixNano() & (1<<53 - 1)
+	if seed == 0 {
+		seed = 1
+	}
+	return seed
+}
+
+// 简单的整数转字符串函数
+func intToString(n int) string {
+	if n == 0 {
+		return "0"
+	}
+
+	var result []byte
+	for n > 0 {
+		result = append([]byte{byte('0' + n%10)}, result...)
+		n /= 10
+	}
+	return string(result)
+}
@@ -0,0 +1,59 @@
+package obfuscator
+
+import (
+	"testing"
+)
+
+// 同样的输入、配置和种子得到逐字节相同的输出；未指定种子时返回实际使用的种子，可以据此复现
+func TestSeedDeterminism(t *testing.T) {
+	src := "function greet(name) { var prefix = 'Hello, '; for (var i = 0; i < 2; i++) { name += '!'; } return prefix + name; }\n" +
+		"var people = { first: 'Ada', second: 'Grace' };\nconsole.log(greet(people.first), greet(people.second), 42, true);\n"
+	config := Config{
+		IdentifierObfuscation: true, StringEncryption: true, StringCipher: CipherRC4, ShuffleBase64Alphabet: true,
+		ControlFlowFlattening: true, DeadCodeInjection: true, ExpressionDecomposition: true,
+		SplitStrings: true, NumbersToExpressions: true, DisguiseLiterals: true, TransformMemberExpressions: true,
+		TransformObjectKeys: true, StringArray: true, StringArrayRotate: true, SelfDefending: true, SourceMap: true,
+		Seed: 12345,
+	}
+	first, err := Obfuscate(src, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Obfuscate(src, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Seed != config.Seed || second.Seed != config.Seed {
+		t.Errorf("返回的种子 %d、%d，期望 %d", first.Seed, second.Seed, config.Seed)
+	}
+	if first.Code != second.Code || first.SourceMap != second.SourceMap {
+		t.Errorf("同一种子的两次输出不同:\n%s\n%s", first.Code, second.Code)
+	}
+
+	config.Seed = 54321
+	other, err := Obfuscate(src, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Code == first.Code {
+		t.Errorf("不同种子的输出相同:\n%s", other.Code)
+	}
+
+	// 未指定种子时自动选取，返回的种子可以复现同样的输出
+	config.Seed = 0
+	auto, err := Obfuscate(src, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auto.Seed == 0 {
+		t.Fatal("未返回自动选取的种子")
+	}
+	config.Seed = auto.Seed
+	replay, err := Obfuscate(src, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.Code != auto.Code {
+		t.Errorf("用返回的种子 %d 无法复现输出:\n%s\n%s", auto.Seed, auto.Code, replay.Code)
+	}
+}
@@ -0,0 +1,168 @@
+package obfuscator
+
+import (
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/token"
+)
+
+func newObjectKeysTransform() Transform {
+	return &builtinTransform{
+		name:        "objectKeys",
+		description: "将对象字面量的属性改为逐个赋值，属性名交给字符串变换处理",
+		options: []Option{
+			{Name: "transformObjectKeys", Type: "boolean", Description: "启用对象键改写"},
+		},
+		enabled: func(cfg *Config) bool { return cfg.TransformObjectKeys },
+		apply: func(ctx *Context) error {
+			transformObjectKeys(ctx)
+			return nil
+		},
+	}
+}
+
+// 单个函数中对象键改写的状态
+type objectKeysFrame struct {
+	body  *[]ast.Statement
+	temps []string // 按对象字面量的嵌套深度使用的临时变量
+	depth int
+}
+
+// 对象键改写
+//
+// 对象字面量改为先创建对象、再逐个给属性赋值：
+//
+//	{apiKey: k, endpoint: u}   →   (t = {}, t['apiKey'] = k, t['endpoint'] = u, t)
+//
+// 属性名成为字符串字面量，之后的字符串变换会隐藏它们。ES5 没有计算属性名，只能这样构造。
+// 赋值顺序与原来的求值顺序和属性顺序一致。getter、setter 以及与它们同名的属性
+// 必须通过字面量定义，它们和之前的属性保留在字面量中；含有 __proto__ 的对象整体保持原样。
+// 与 Object.prototype 上的属性同名的键同样保留在字面量中：赋值会经过原型上的属性，
+// 原型被冻结时赋值不会生效，字面量则总是定义自有属性。
+// 临时变量按嵌套深度分配，声明在所在函数的开头；with 语句及其中的函数不做处理。
+func transformObjectKeys(ctx *Context) {
+	frames := []*objectKeysFrame{{body: &ctx.Program.Body}}
+	withDepth := 0
+	walker := &astWalker{
+		enter: func(node ast.Node) bool {
+			frame := frames[len(frames)-1]
+			switch n := node.(type) {
+			case *ast.WithStatement:
+				withDepth++
+			case *ast.FunctionLiteral:
+				if body, ok := n.Body.(*ast.BlockStatement); ok {
+					frames = append(frames, &objectKeysFrame{body: &body.List})
+				}
+			case *ast.ObjectLiteral:
+				frame.depth++
+			}
+			return true
+		},
+		leave: func(node ast.Node) ast.Node {
+			frame := frames[len(frames)-1]
+			switch n := node.(type) {
+			case *ast.WithStatement:
+				withDepth--
+			case *ast.FunctionLiteral:
+				if _, ok := n.Body.(*ast.BlockStatement); ok {
+					declareObjectTemps(frame)
+					frames = frames[:len(frames)-1]
+				}
+			case *ast.ObjectLiteral:
+				frame.depth--
+				if withDepth > 0 {
+					return node
+				}
+				split := objectKeysSplit(n)
+				if split < 0 || split == len(n.Value) {
+					return node
+				}
+				// 内层对象先处理，临时变量可能需要一次补齐多个
+				for len(frame.temps) <= frame.depth {
+					frame.temps = append(frame.temps, ctx.newName())
+				}
+				return buildObject(n, split, frame.temps[frame.depth])
+			}
+			return node
+		},
+	}
+	walker.walk(ctx.Program)
+	declareObjectTemps(frames[0])
+}
+
+// Object.prototype 上的属性名
+var objectPrototypeProperties = map[string]bool{
+	"constructor":          true,
+	"toString":             true,
+	"toLocaleString":       true,
+	"valueOf":              true,
+	"hasOwnProperty":       true,
+	"isPrototypeOf":        true,
+	"propertyIsEnumerable": true,
+	"__defineGetter__":     true,
+	"__defineSetter__":     true,
+	"__lookupGetter__":     true,
+	"__lookupSetter__":     true,
+}
+
+// 保留在字面量中的属性个数，返回 -1 表示整个对象保持原样
+func objectKeysSplit(object *ast.ObjectLiteral) int {
+	accessors := make(map[string]bool)
+	for _, prop := range object.Value {
+		// 字面量中的 __proto__ 设置原型，原型上的 setter 会改变之后赋值的行为
+		if prop.Key == "__proto__" {
+			return -1
+		}
+		if prop.Kind != "value" {
+			accessors[prop.Key] = true
+		}
+	}
+	split := 0
+	for i, prop := range object.Value {
+		if prop.Kind != "value" || accessors[prop.Key] || objectPrototypeProperties[prop.Key] {
+			split = i + 1
+		}
+	}
+	return split
+}
+
+// 用临时变量构造对象：(t = {保留的属性}, t['k'] = v, ..., t)
+func buildObject(object *ast.ObjectLiteral, split int, temp string) ast.Expression {
+	sequence := []ast.Expression{&ast.AssignExpression{
+		Operator: token.ASSIGN,
+		Left:     &ast.Identifier{Name: temp},
+		Right: &ast.ObjectLiteral{
+			LeftBrace:  object.LeftBrace,
+			RightBrace: object.RightBrace,
+			Value:      object.Value[:split],
+		},
+	}}
+	for _, prop := range object.Value[split:] {
+		sequence = append(sequence, &ast.AssignExpression{
+			Operator: token.ASSIGN,
+			Left: &ast.BracketExpression{
+				Left:   &ast.Identifier{Name: temp},
+				Member: &ast.StringLiteral{Value: prop.Key},
+			},
+			Right: prop.Value,
+		})
+	}
+	sequence = append(sequence, &ast.Identifier{Name: temp})
+	expr := &ast.SequenceExpression{Sequence: sequence}
+	inheritPosition(expr, object.LeftBrace)
+	return expr
+}
+
+// 在函数体的指令序言之后声明临时变量
+func declareObjectTemps(frame *objectKeysFrame) {
+	if len(frame.temps) == 0 {
+		return
+	}
+	list := make([]ast.Expression, len(frame.temps))
+	for i, name := range frame.temps {
+		list[i] = &ast.VariableExpression{Name: name}
+	}
+	directives, rest := splitDirectives(*frame.body)
+	result := append([]ast.Statement(nil), directives...)
+	result = append(result, &ast.VariableStatement{List: list})
+	*frame.body = append(result, rest...)
+}
@@ -0,0 +1,33 @@
+package obfuscator
+
+import (
+	"strings"
+	"testing"
+)
+
+// 对象键改写前后的运行结果相同：属性值、枚举顺序、求值顺序和访问器都不变
+func TestObjectKeysEquivalence(t *testing.T) {
+	node := requireNode(t)
+	src := strings.Join([]string{
+		"var log = [];",
+		"function f(tag, v) { log.push(tag); return v; }",
+		"function show(o) { var keys = []; for (var k in o) keys.push(k + ':' + (typeof o[k] === 'function' ? 'fn' : o[k])); log.push(keys.join('/')); }",
+		"show({ b: f('b', 1), a: f('a', 2), 1: f('1', 3), 'c d': f('cd', 4), nested: { x: f('x', 5), y: { z: 6 } }.y.z });",
+		"show({ 0x10: 'hex', 1.50: 'float', 2: 'int', a: 'str' });",
+		"var acc = { v: 1, get w() { return this.v * 2; }, set w(x) { this.v = x; }, after: 3 };",
+		"acc.w = 5; show(acc); log.push(acc.w);",
+		"var proto = { __proto__: { inherited: 1 }, own: 2 }; log.push(proto.inherited, Object.keys(proto).join());",
+		"var dup = { a: 1, b: 2, a: 3 }; show(dup);",
+		// Object.prototype 被冻结后，同名的键只能通过字面量定义为自有属性
+		"Object.freeze(Object.prototype);",
+		"var frozen = { id: 7, toString: function () { return 'custom'; }, valueOf: function () { return 42; }, constructor: 'c', hasOwnProperty: 'h', tail: 8 };",
+		"log.push(String(frozen), frozen + 0, frozen.constructor, frozen.hasOwnProperty, frozen.tail);",
+		"show(frozen);",
+		"console.log(log.join());",
+	}, "\n")
+	configs := map[string]Config{
+		"objectKeys": {TransformObjectKeys: true},
+		"combined":   {TransformObjectKeys: true, StringArray: true, StringEncryption: true, IdentifierObfuscation: true},
+	}
+	checkEquivalent(t, node, src, configs, 3)
+}
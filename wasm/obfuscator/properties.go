@@ -0,0 +1,225 @@
+package obfuscator
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/token"
+)
+
+func newPropertiesTransform() Transform {
+	return &builtinTransform{
+		name:        "properties",
+		description: "按正则表达式统一改名匹配的属性名",
+		options: []Option{
+			{Name: "mangleProperties", Type: "boolean", Description: "启用属性名改名"},
+			{Name: "manglePropertiesRegex", Type: "string", Description: "需要改名的属性名的正则表达式（如 ^_）"},
+		},
+		enabled: func(cfg *Config) bool { return cfg.MangleProperties },
+		apply: func(ctx *Context) error {
+			propertyMap, err := mangleProperties(ctx)
+			if err != nil {
+				return err
+			}
+			ctx.PropertyMap = propertyMap
+			return nil
+		},
+	}
+}
+
+// ECMAScript 内置对象上的属性名，匹配正则表达式也不改名
+//
+// 包括全局对象的属性、内置构造函数和命名空间对象（Math、JSON 等）的静态成员，以及各原型上的方法和访问器。
+const esPropertyNames = `
+	constructor prototype __proto__ length name arguments caller callee
+	toString toLocaleString valueOf hasOwnProperty isPrototypeOf propertyIsEnumerable
+	__defineGetter__ __defineSetter__ __lookupGetter__ __lookupSetter__
+	apply bind call
+	globalThis Infinity NaN undefined eval isFinite isNaN parseFloat parseInt
+	decodeURI decodeURIComponent encodeURI encodeURIComponent escape unescape
+	Array ArrayBuffer Boolean DataView Date Error EvalError Function JSON Map Math
+	Number Object Promise Proxy RangeError ReferenceError Reflect RegExp Set String
+	Symbol SyntaxError TypeError URIError WeakMap WeakSet
+	create defineProperty defineProperties getOwnPropertyDescriptor
+	getOwnPropertyDescriptors getOwnPropertyNames getOwnPropertySymbols getPrototypeOf
+	setPrototypeOf preventExtensions isExtensible seal isSealed freeze isFrozen
+	assign is fromEntries groupBy hasOwn ownKeys construct deleteProperty
+	isArray from of
+	concat copyWithin entries every fill filter find findIndex findLast findLastIndex
+	flat flatMap forEach includes indexOf join keys lastIndexOf map pop push reduce
+	reduceRight reverse shift slice some sort splice unshift values at
+	toReversed toSorted toSpliced with
+	fromCharCode fromCodePoint raw
+	charAt charCodeAt codePointAt endsWith localeCompare match matchAll normalize
+	padEnd padStart repeat replace replaceAll search split startsWith substr substring
+	toLowerCase toUpperCase toLocaleLowerCase toLocaleUpperCase trim trimEnd trimStart
+	trimLeft trimRight anchor big blink bold fixed fontcolor fontsize italics link small
+	strike sub sup
+	exec test compile source flags global ignoreCase multiline sticky unicode dotAll
+	hasIndices lastIndex index input groups
+	EPSILON MAX_SAFE_INTEGER MIN_SAFE_INTEGER MAX_VALUE MIN_VALUE
+	NEGATIVE_INFINITY POSITIVE_INFINITY isInteger isSafeInteger
+	toFixed toExponential toPrecision
+	E LN10 LN2 LOG10E LOG2E PI SQRT1_2 SQRT2
+	abs acos acosh asin asinh atan atan2 atanh cbrt ceil clz32 cos cosh exp expm1
+	floor fround hypot imul log log10 log1p log2 max min pow random round sign sin
+	sinh sqrt tan tanh trunc
+	parse stringify
+	UTC now
+	getDate getDay getFullYear getHours getMilliseconds getMinutes getMonth
+	getSeconds getTime getTimezoneOffset getUTCDate getUTCDay getUTCFullYear
+	getUTCHours getUTCMilliseconds getUTCMinutes getUTCMonth getUTCSeconds getYear
+	setDate setFullYear setHours setMilliseconds setMinutes setMonth setSeconds
+	setTime setUTCDate setUTCFullYear setUTCHours setUTCMilliseconds setUTCMinutes
+	setUTCMonth setUTCSeconds setYear toDateString toGMTString toISOString toJSON
+	toLocaleDateString toLocaleTimeString toTimeString toUTCString
+	then catch finally resolve reject all allSettled any race withResolvers
+	get set has delete clear add size next done value return throw
+	message stack cause errors
+	iterator asyncIterator hasInstance isConcatSpreadable species toPrimitive
+	toStringTag unscopables description for keyFor
+	enumerable configurable writable
+	byteLength byteOffset buffer isView BYTES_PER_ELEMENT subarray
+	getInt8 getUint8 getInt16 getUint16 getInt32 getUint32 getFloat32 getFloat64
+	setInt8 setUint8 setInt16 setUint16 setInt32 setUint32 setFloat32 setFloat64
+`
+
+// 宿主环境（DOM、Node.js）中常见的属性名
+const hostPropertyNames = `
+	document window self parent top location navigator history localStorage sessionStorage
+	addEventListener removeEventListener dispatchEvent preventDefault stopPropagation
+	target currentTarget type detail data key code keyCode which button
+	getElementById getElementsByClassName getElementsByTagName querySelector querySelectorAll
+	createElement createTextNode appendChild removeChild insertBefore replaceChild
+	cloneNode contains parentNode childNodes children firstChild lastChild
+	nextSibling previousSibling nodeType nodeName nodeValue
+	innerHTML outerHTML innerText textContent id className classList style
+	getAttribute setAttribute removeAttribute hasAttribute dataset
+	href src alt title width height left right bottom
+	offsetWidth offsetHeight clientWidth clientHeight scrollTop scrollLeft
+	focus blur click submit reset checked disabled selected options
+	onload onerror onclick onchange oninput onmessage
+	readyState status statusText responseText response open send abort
+	headers body json text ok url method
+	exports module require default
+	console log info warn error debug trace dir dirxml table group groupCollapsed
+	groupEnd time timeEnd timeLog count countReset assert profile profileEnd
+`
+
+// 内置属性名
+var builtinProperties = func() map[string]bool {
+	names := make(map[string]bool)
+	for _, list := range []string{esPropertyNames, hostPropertyNames} {
+		for _, name := range strings.Fields(list) {
+			names[name] = true
+		}
+	}
+	return names
+}()
+
+// 属性名改名
+//
+// 名称匹配 manglePropertiesRegex 的属性在整个输入中统一改名，包括 a.b、a['b']、
+// 'b' in a 和对象字面量的键。内置属性名、目标环境的全局名（window.fetch 等通过全局对象访问的名称）
+// 和用户保留的名称（reservedNames、reservedNamePatterns）即使匹配也保持原样。
+// propertyMap 中已有的属性沿用原来的新名称，返回的映射包含它的全部条目，
+// 保存下来供之后的构建使用，分开混淆的文件之间仍然一致。
+// 通过拼接字符串等方式动态得到的属性名无法识别，需要避免被匹配或加入保留名称。
+func mangleProperties(ctx *Context) (map[string]string, error) {
+	pattern := ctx.Config.ManglePropertiesRegex
+	if pattern == "" {
+		return nil, errors.New("mangleProperties 需要指定 manglePropertiesRegex")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errors.New("无效的属性名模式: " + pattern)
+	}
+
+	propertyMap := make(map[string]string, len(ctx.Config.PropertyMap))
+	used := make(map[string]bool)
+	for original, mangled := range ctx.Config.PropertyMap {
+		if !isIdentifierName(mangled) {
+			return nil, errors.New("属性名映射中的名称无效: " + mangled)
+		}
+		propertyMap[original] = mangled
+		used[mangled] = true
+	}
+
+	// 程序中出现的所有属性名和字符串都不能用作新名称
+	var sites []propertySite
+	walker := &astWalker{
+		enter: func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.DotExpression:
+				used[n.Identifier.Name] = true
+				sites = append(sites, propertySite{name: &n.Identifier.Name})
+			case *ast.BracketExpression:
+				if literal, ok := n.Member.(*ast.StringLiteral); ok {
+					sites = append(sites, propertySite{name: &literal.Value, literal: literal})
+				}
+			case *ast.BinaryExpression:
+				if literal, ok := n.Left.(*ast.StringLiteral); ok && n.Operator == token.IN {
+					sites = append(sites, propertySite{name: &literal.Value, literal: literal})
+				}
+			case *ast.ObjectLiteral:
+				for i := range n.Value {
+					used[n.Value[i].Key] = true
+					sites = append(sites, propertySite{name: &n.Value[i].Key})
+				}
+			case *ast.StringLiteral:
+				used[n.Value] = true
+			}
+			return true
+		},
+	}
+	walker.walk(ctx.Program)
+
+	mangle := func(name string) bool {
+		return re.MatchString(name) && !builtinProperties[name] && !ctx.IsGlobal(name) && !ctx.IsReserved(name)
+	}
+	// 新名称按属性名排序后分配，同样的输入和种子得到同样的映射
+	var pending []string
+	for _, site := range sites {
+		name := *site.name
+		if _, ok := propertyMap[name]; !ok && mangle(name) {
+			propertyMap[name] = ""
+			pending = append(pending, name)
+		}
+	}
+	sort.Strings(pending)
+	for _, name := range pending {
+		propertyMap[name] = newPropertyName(ctx, used)
+	}
+
+	for _, site := range sites {
+		if !mangle(*site.name) {
+			continue
+		}
+		*site.name = propertyMap[*site.name]
+		// 字符串的原写法已经失效，由代码生成器按新的值输出
+		if site.literal != nil {
+			site.literal.Literal = ""
+		}
+	}
+	return propertyMap, nil
+}
+
+// 属性名出现的位置
+type propertySite struct {
+	name    *string
+	literal *ast.StringLiteral // 属性名来自字符串字面量时不为 nil
+}
+
+// 生成一个未被使用的属性名
+func newPropertyName(ctx *Context, used map[string]bool) string {
+	for {
+		name := "_0x" + intToHex(0x100000+ctx.Rand.Intn(0xf00000))
+		if !used[name] {
+			used[name] = true
+			return name
+		}
+	}
+}
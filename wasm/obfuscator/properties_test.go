@@ -0,0 +1,72 @@
+package obfuscator
+
+import (
+	"strings"
+	"testing"
+)
+
+// 保留名称即使匹配 manglePropertiesRegex 也不改名
+func TestManglePropertiesKeepsReservedNames(t *testing.T) {
+	src := "var o = { _keep: 1, _kept2: 2, _drop: 3 }; o._keep + o['_kept2'] + o._drop;"
+	result, err := Obfuscate(src, Config{
+		MangleProperties:      true,
+		ManglePropertiesRegex: "^_",
+		ReservedNames:         []string{"_keep"},
+		ReservedNamePatterns:  []string{"^_kept"},
+		Seed:                  1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"_keep", "_kept2"} {
+		if _, ok := result.PropertyMap[name]; ok || strings.Count(result.Code, name) != 2 {
+			t.Errorf("保留名称 %s 被改名:\n%s", name, result.Code)
+		}
+	}
+	if _, ok := result.PropertyMap["_drop"]; !ok || strings.Contains(result.Code, "_drop") {
+		t.Errorf("_drop 没有改名:\n%s", result.Code)
+	}
+}
+
+// 正则表达式匹配几乎所有名称时，内置对象的成员和全局名仍然保持原样，运行结果不变
+func TestManglePropertiesKeepsBuiltins(t *testing.T) {
+	node := requireNode(t)
+	src := strings.Join([]string{
+		"var stats = { total: 0, items: [] };",
+		"function record(value) { stats.items.push(value); stats.total += value; return stats; }",
+		"record(Math.floor(2.7)); record(Math.max(1, 5, 3)); record(Math.min(4, 2)); record(Math.abs(-3));",
+		"record(Math.round(Math.random() * 0) + Math.pow(2, 3) + Math.ceil(Math.PI) + Math.sqrt(16));",
+		// JSON 和 defineProperty 中的名称是动态的，用不匹配的大写开头
+		"var parsed = JSON.parse('{\"Count\": 2, \"List\": [1, 2]}');",
+		"var text = JSON.stringify([stats.total, parsed.Count, parsed.List.length]);",
+		"var proto = Object.create({ greet: function () { return 'hi'; } });",
+		"Object.defineProperty(proto, 'Hidden', { value: 1, enumerable: false });",
+		"var keys = Object.keys({ a: 1, b: 2 }).concat(Object.getOwnPropertyNames(proto));",
+		"console.log(text, stats.items.join('-'), Array.isArray(stats.items), typeof Date.now(),",
+		"  proto.greet(), proto.Hidden, keys.length, String.fromCharCode(72), Number.isFinite(1),",
+		"  typeof globalThis.setTimeout, typeof globalThis.queueMicrotask);",
+		"console.info(stats.total.toFixed(1), 'abc'.toUpperCase().charCodeAt(0));",
+	}, "\n")
+	configs := map[string]Config{
+		"mangle":  {MangleProperties: true, ManglePropertiesRegex: "^[a-z]", Target: TargetNode},
+		"browser": {MangleProperties: true, ManglePropertiesRegex: "^[a-z]"},
+	}
+	checkEquivalent(t, node, src, configs, 3)
+
+	result, err := Obfuscate("window.fetch(url); self.postMessage(msg); obj.custom = 1;", Config{
+		MangleProperties:      true,
+		ManglePropertiesRegex: "^[a-z]",
+		Seed:                  1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"fetch", "postMessage"} {
+		if !strings.Contains(result.Code, "."+name+"(") {
+			t.Errorf("全局名 %s 被改名:\n%s", name, result.Code)
+		}
+	}
+	if _, ok := result.PropertyMap["custom"]; !ok {
+		t.Errorf("custom 没有改名:\n%s", result.Code)
+	}
+}
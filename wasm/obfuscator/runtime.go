@@ -0,0 +1,80 @@
+package obfuscator
+
+import (
+	"strings"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/parser"
+)
+
+// 解析变换插入的运行时代码
+//
+// 模板与源码登记在同一个 FileSet 中，节点位置都落在源码范围之外，
+// source map 不会把它们映射到源码。names 中的占位符按原样替换为对应的名称。
+// 模板是内置常量，解析失败属于程序错误。
+func (ctx *Context) parseRuntime(src string, names map[string]string) []ast.Statement {
+	if len(names) > 0 {
+		pairs := make([]string, 0, len(names)*2)
+		for placeholder, name := range names {
+			pairs = append(pairs, placeholder, name)
+		}
+		src = strings.NewReplacer(pairs...).Replace(src)
+	}
+	program, err := parser.ParseFile(ctx.fileSet, "", src, 0)
+	if err != nil {
+		panic("运行时代码模板无效: " + err.Error())
+	}
+	return program.Body
+}
+
+// 生成一个在程序中未被使用的名称
+func (ctx *Context) newName() string {
+	if ctx.names == nil {
+		ctx.names = collectNames(ctx.Program)
+	}
+	for {
+		name := "_0x" + intToHex(0x100000+ctx.Rand.Intn(0xf00000))
+		if !ctx.names[name] && !ctx.IsReserved(name) && !ctx.IsGlobal(name) {
+			ctx.names[name] = true
+			return name
+		}
+	}
+}
+
+// 收集程序中出现的全部变量名、函数名和参数名
+func collectNames(program *ast.Program) map[string]bool {
+	names := make(map[string]bool)
+	walker := &astWalker{
+		enter: func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.Identifier:
+				names[n.Name] = true
+			case *ast.VariableExpression:
+				names[n.Name] = true
+			case *ast.FunctionLiteral:
+				if n.Name != nil {
+					names[n.Name.Name] = true
+				}
+				if n.ParameterList != nil {
+					for _, param := range n.ParameterList.List {
+						names[param.Name] = true
+					}
+				}
+			case *ast.CatchStatement:
+				names[n.Parameter.Name] = true
+			}
+			return true
+		},
+	}
+	walker.walk(program)
+	return names
+}
+
+// 在指令序言之后插入语句
+func prependStatements(program *ast.Program, stmts ...ast.Statement) {
+	directives, rest := splitDirectives(program.Body)
+	body := make([]ast.Statement, 0, len(program.Body)+len(stmts))
+	body = append(body, directives...)
+	body = append(body, stmts...)
+	program.Body = append(body, rest...)
+}
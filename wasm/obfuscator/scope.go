@@ -0,0 +1,312 @@
+package obfuscator
+
+import (
+	"strings"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+)
+
+// 作用域类型
+type scopeKind int
+
+const (
+	scopeGlobal       scopeKind = iota // 程序顶层
+	scopeFunction                      // 函数体：参数、var 和函数声明
+	scopeFunctionName                  // 具名函数表达式的名称，只在函数内部可见
+	scopeCatch                         // catch 子句的参数
+)
+
+func (k scopeKind) String() string {
+	switch k {
+	case scopeGlobal:
+		return "global"
+	case scopeFunction:
+		return "function"
+	case scopeFunctionName:
+		return "functionName"
+	case scopeCatch:
+		return "catch"
+	}
+	return "unknown"
+}
+
+// 绑定类型
+type bindingKind int
+
+const (
+	bindingVar bindingKind = iota
+	bindingFunction
+	bindingParameter
+	bindingFunctionName
+	bindingCatch
+)
+
+func (k bindingKind) String() string {
+	switch k {
+	case bindingVar:
+		return "var"
+	case bindingFunction:
+		return "function"
+	case bindingParameter:
+		return "parameter"
+	case bindingFunctionName:
+		return "functionName"
+	case bindingCatch:
+		return "catch"
+	}
+	return "unknown"
+}
+
+// 作用域
+type scope struct {
+	kind     scopeKind
+	parent   *scope
+	children []*scope
+	node     ast.Node // *ast.Program、*ast.FunctionLiteral 或 *ast.CatchStatement
+	bindings map[string]*binding
+	order    []*binding
+}
+
+// 绑定：一次声明以及解析到它的全部引用
+type binding struct {
+	name  string
+	kind  bindingKind
+	scope *scope
+	idx   file.Idx // 第一次声明的位置
+	// sites 声明和引用处名称字段的指针，改名时统一改写
+	sites []*string
+	// frozen 为 true 时无法静态确定全部引用（with、eval 等），必须保留原名
+	frozen bool
+}
+
+func (b *binding) rename(name string) {
+	for _, site := range b.sites {
+		*site = name
+	}
+	b.name = name
+}
+
+// 作用域分析结果
+type scopeTree struct {
+	root     *scope
+	scopes   map[ast.Node]*scope
+	bindings []*binding      // 全部绑定，按声明顺序
+	free     map[string]bool // 没有声明、直接引用的全局名称
+}
+
+// 查找名称在给定作用域中解析到的绑定
+func (s *scope) resolve(name string) *binding {
+	for current := s; current != nil; current = current.parent {
+		if b, ok := current.bindings[name]; ok {
+			return b
+		}
+	}
+	return nil
+}
+
+// 作用域的可读描述：由外到内的函数名路径，如 "outer/(anonymous)/catch"
+func (s *scope) describe() string {
+	var path []string
+	for current := s; current != nil; current = current.parent {
+		switch current.kind {
+		case scopeFunction:
+			name := "(anonymous)"
+			if fn, ok := current.node.(*ast.FunctionLiteral); ok && fn.Name != nil {
+				name = fn.Name.Name
+			}
+			path = append(path, name)
+		case scopeCatch:
+			path = append(path, "catch")
+		}
+	}
+	if len(path) == 0 {
+		return "global"
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return strings.Join(path, "/")
+}
+
+// 最近的函数作用域（或全局作用域）
+func (s *scope) functionScope() *scope {
+	current := s
+	for current.kind != scopeFunction && current.kind != scopeGlobal {
+		current = current.parent
+	}
+	return current
+}
+
+// 作用域分析器
+type scopeAnalyzer struct {
+	tree         *scopeTree
+	current      *scope
+	declarations map[*ast.FunctionLiteral]bool
+	withDepth    int
+}
+
+// 构建作用域树并解析全部标识符引用
+func analyzeScopes(program *ast.Program) *scopeTree {
+	a := &scopeAnalyzer{
+		tree: &scopeTree{
+			scopes: make(map[ast.Node]*scope),
+			free:   make(map[string]bool),
+		},
+		declarations: make(map[*ast.FunctionLiteral]bool),
+	}
+	a.current = a.push(scopeGlobal, program)
+	a.tree.root = a.current
+	a.hoist(program.Body)
+	a.visit(program)
+	return a.tree
+}
+
+func (a *scopeAnalyzer) push(kind scopeKind, node ast.Node) *scope {
+	s := &scope{
+		kind:     kind,
+		parent:   a.current,
+		node:     node,
+		bindings: make(map[string]*binding),
+	}
+	if a.current != nil {
+		a.current.children = append(a.current.children, s)
+	}
+	a.tree.scopes[node] = s
+	a.current = s
+	return s
+}
+
+func (a *scopeAnalyzer) pop() {
+	a.current = a.current.parent
+}
+
+// 在当前作用域声明名称，重复声明合并到同一个绑定
+func (a *scopeAnalyzer) declare(s *scope, name string, kind bindingKind, idx file.Idx, site *string) *binding {
+	b, ok := s.bindings[name]
+	if !ok {
+		b = &binding{name: name, kind: kind, scope: s, idx: idx}
+		s.bindings[name] = b
+		s.order = append(s.order, b)
+		a.tree.bindings = append(a.tree.bindings, b)
+	} else if kind == bindingFunction {
+		b.kind = kind
+	}
+	if site != nil {
+		b.sites = append(b.sites, site)
+	}
+	return b
+}
+
+// 提升函数体内的 var 和函数声明，不进入嵌套函数
+func (a *scopeAnalyzer) hoist(body []ast.Statement) {
+	s := a.current
+	w := &astWalker{
+		enter: func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.FunctionStatement:
+				a.declarations[n.Function] = true
+				if n.Function.Name != nil {
+					a.declare(s, n.Function.Name.Name, bindingFunction, n.Function.Name.Idx, &n.Function.Name.Name)
+				}
+				return false
+			case *ast.FunctionLiteral:
+				return false
+			case *ast.VariableExpression:
+				a.declare(s, n.Name, bindingVar, n.Idx, nil)
+			}
+			return true
+		},
+	}
+	for _, stmt := range body {
+		w.walk(stmt)
+	}
+}
+
+// 解析引用
+func (a *scopeAnalyzer) visit(node ast.Node) {
+	w := &astWalker{enter: a.enter}
+	w.walk(node)
+}
+
+func (a *scopeAnalyzer) enter(node ast.Node) bool {
+	switch n := node.(type) {
+	case *ast.FunctionLiteral:
+		a.function(n)
+		return false
+	case *ast.CatchStatement:
+		a.push(scopeCatch, n)
+		a.declare(a.current, n.Parameter.Name, bindingCatch, n.Parameter.Idx, &n.Parameter.Name)
+		a.visit(n.Body)
+		a.pop()
+		return false
+	case *ast.WithStatement:
+		a.visit(n.Object)
+		a.withDepth++
+		a.visit(n.Body)
+		a.withDepth--
+		return false
+	case *ast.Identifier:
+		a.reference(n.Name, &n.Name)
+	case *ast.VariableExpression:
+		b := a.reference(n.Name, &n.Name)
+		// catch 块中与参数同名的 var：声明属于函数作用域，赋值却落在 catch 参数上
+		if b != nil && b.scope.kind == scopeCatch {
+			b.frozen = true
+			if outer, ok := a.current.functionScope().bindings[n.Name]; ok {
+				outer.frozen = true
+			}
+		}
+	case *ast.CallExpression:
+		// 直接调用 eval 可以访问所有外层作用域中的变量
+		if callee, ok := n.Callee.(*ast.Identifier); ok && callee.Name == "eval" && a.current.resolve("eval") == nil {
+			for s := a.current; s != nil; s = s.parent {
+				for _, b := range s.order {
+					b.frozen = true
+				}
+			}
+		}
+	}
+	return true
+}
+
+func (a *scopeAnalyzer) function(fn *ast.FunctionLiteral) {
+	// 具名函数表达式的名称只在函数内部可见
+	named := fn.Name != nil && !a.declarations[fn]
+	if named {
+		a.push(scopeFunctionName, fn.Name)
+		a.declare(a.current, fn.Name.Name, bindingFunctionName, fn.Name.Idx, &fn.Name.Name)
+	}
+
+	a.push(scopeFunction, fn)
+	if fn.ParameterList != nil {
+		for _, param := range fn.ParameterList.List {
+			a.declare(a.current, param.Name, bindingParameter, param.Idx, &param.Name)
+		}
+	}
+	if body, ok := fn.Body.(*ast.BlockStatement); ok {
+		a.hoist(body.List)
+	}
+
+	// withDepth 不清零：with 内部定义的函数仍然可能通过 with 对象解析名称
+	a.visit(fn.Body)
+	a.pop()
+
+	if named {
+		a.pop()
+	}
+}
+
+// 记录一次引用，返回解析到的绑定；未声明的名称记为全局名称
+func (a *scopeAnalyzer) reference(name string, site *string) *binding {
+	b := a.current.resolve(name)
+	if b == nil {
+		a.tree.free[name] = true
+		return nil
+	}
+	b.sites = append(b.sites, site)
+	if a.withDepth > 0 {
+		b.frozen = true
+	}
+	return b
+}
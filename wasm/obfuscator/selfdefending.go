@@ -0,0 +1,202 @@
+package obfuscator
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/robertkrimen/otto/ast"
+)
+
+func newSelfDefendingTransform() Transform {
+	return &builtinTransform{
+		name:        "selfDefending",
+		description: "插入自我保护代码，输出被格式化或改动后无法正常运行",
+		options: []Option{
+			{Name: "selfDefending", Type: "boolean", Description: "启用自我保护（强制压缩输出）"},
+		},
+		enabled: func(cfg *Config) bool { return cfg.SelfDefending },
+		apply: func(ctx *Context) error {
+			injectSelfDefending(ctx)
+			return nil
+		},
+	}
+}
+
+// 自我保护检查
+//
+// 检查函数计算自身和若干顶层函数源码（toString）的校验值，与生成代码后算出的期望值比较，
+// 结果存入 __RESULT__：一致时为 0，否则为 1~63 之间的值。
+// 校验值只用乘 33 累加再取模，所有中间结果都在双精度浮点数能精确表示的范围内。
+// 成员名写成转义后的字符串下标，输出中搜不到 toString、charCodeAt 等名称。
+const selfDefendingTemplate = `
+var __RESULT__ = (function __CHECK__(__FUNCTIONS__, __EXPECTED__) {
+	var __HASH__ = 5381;
+	__FUNCTIONS__ = [__CHECK__][__CONCAT__](__FUNCTIONS__);
+	for (var __I__ = 0; __I__ < __FUNCTIONS__[__LENGTH__]; __I__++) {
+		var __SOURCE__ = __FUNCTIONS__[__I__][__TO_STRING__]();
+		for (var __J__ = 0; __J__ < __SOURCE__[__LENGTH__]; __J__++) {
+			__HASH__ = (__HASH__ * 33 + __SOURCE__[__CHAR_CODE_AT__](__J__)) % 2147483647;
+		}
+	}
+	return __HASH__ === __EXPECTED__ ? 0 : 1 + __HASH__ % 63;
+})([__LIST__], 0);
+`
+
+// 检查结果不为 0 时进入死循环；插入在检查之后和每个参与校验的函数开头，
+// 单独删除检查语句会让这些函数因为变量未定义而出错
+const selfDefendingGuard = `if (__RESULT__) { for (;;) {} }`
+
+// 除检查函数自身以外参与校验的顶层函数个数上限
+const maxSelfDefendingFunctions = 3
+
+// 期望值的占位写法，生成代码后原位替换为同样长度的十六进制数，source map 不受影响
+const selfDefendingPlaceholder = "0x00000000"
+
+// 一次混淆中的自我保护检查
+type selfDefendingCheck struct {
+	result    string                 // 检查结果的变量名
+	functions []*ast.FunctionLiteral // 参与校验的函数，第一个是检查函数
+	list      *ast.ArrayLiteral      // 传给检查函数的函数列表
+	expected  *ast.NumberLiteral     // 期望的校验值，生成代码后填入
+}
+
+// 自我保护检查，首次调用时创建并分配结果变量名
+//
+// 字符串解码函数通过结果变量把检查结果混入解码过程。
+func (ctx *Context) selfDefendingCheck() *selfDefendingCheck {
+	if ctx.selfDefending == nil {
+		ctx.selfDefending = &selfDefendingCheck{result: ctx.newName()}
+	}
+	return ctx.selfDefending
+}
+
+// 自我保护
+//
+// 在成员表达式和字符串变换之前插入检查代码，检查中的成员名和字符串随之一起被改写。
+// 参与校验的是检查函数自身、随机选出的几个顶层函数声明和之后插入的 Base64 解码函数，
+// 它们在检查执行时已经提升，可以直接引用。之后的变换可以改动这些函数，
+// 校验值按生成的代码计算，生成之后才填入期望值。代码被格式化或改动后校验值改变：
+// 检查处进入死循环，被校验的函数一调用就进入死循环，Base64 解码出的字符串全部错误。
+// 压缩输出几乎不含换行，不会因为换行符转换而误判，打开 selfDefending 时输出总是压缩的。
+func injectSelfDefending(ctx *Context) {
+	check := ctx.selfDefendingCheck()
+
+	// 同名的函数声明以最后一个为准
+	declared := make(map[string]*ast.FunctionLiteral)
+	var names []string
+	_, body := splitDirectives(ctx.Program.Body)
+	for _, stmt := range body {
+		if fn, ok := stmt.(*ast.FunctionStatement); ok && fn.Function.Name != nil {
+			name := fn.Function.Name.Name
+			if declared[name] == nil {
+				names = append(names, name)
+			}
+			declared[name] = fn.Function
+		}
+	}
+	var chosen []string
+	for _, i := range ctx.Rand.Perm(len(names)) {
+		if len(chosen) == maxSelfDefendingFunctions {
+			break
+		}
+		chosen = append(chosen, names[i])
+	}
+
+	stmts := ctx.parseRuntime(selfDefendingTemplate, map[string]string{
+		"__RESULT__":    check.result,
+		"__CHECK__":     ctx.newName(),
+		"__FUNCTIONS__": ctx.newName(),
+		"__EXPECTED__":  ctx.newName(),
+		"__HASH__":      ctx.newName(),
+		"__I__":         ctx.newName(),
+		"__SOURCE__":    ctx.newName(),
+		"__J__":         ctx.newName(),
+		"__LIST__":      strings.Join(chosen, ", "),
+
+		"__CONCAT__":       escapedMemberName(ctx, "concat"),
+		"__LENGTH__":       escapedMemberName(ctx, "length"),
+		"__TO_STRING__":    escapedMemberName(ctx, "toString"),
+		"__CHAR_CODE_AT__": escapedMemberName(ctx, "charCodeAt"),
+	})
+	call := stmts[0].(*ast.VariableStatement).List[0].(*ast.VariableExpression).Initializer.(*ast.CallExpression)
+	// 先于检查插入的解码函数排在选出的函数之后
+	earlier := check.functions
+	check.functions = []*ast.FunctionLiteral{call.Callee.(*ast.FunctionLiteral)}
+	check.list = call.ArgumentList[0].(*ast.ArrayLiteral)
+	check.expected = call.ArgumentList[1].(*ast.NumberLiteral)
+	check.expected.Literal = selfDefendingPlaceholder
+
+	guard := func() ast.Statement {
+		return ctx.parseRuntime(selfDefendingGuard, map[string]string{"__RESULT__": check.result})[0]
+	}
+	for _, name := range chosen {
+		fn := declared[name]
+		check.functions = append(check.functions, fn)
+		block := fn.Body.(*ast.BlockStatement)
+		directives, rest := splitDirectives(block.List)
+		list := append(append([]ast.Statement(nil), directives...), guard())
+		block.List = append(list, rest...)
+	}
+	for _, fn := range earlier {
+		if !check.includes(fn) {
+			check.include(fn)
+		}
+	}
+	prependStatements(ctx.Program, append(stmts, guard())...)
+}
+
+// 成员名的转义字符串写法
+func escapedMemberName(ctx *Context, name string) string {
+	units := utf16.Encode([]rune(name))
+	if ctx.Rand.Intn(2) == 0 {
+		return encodeStringAsHex(units).(*ast.StringLiteral).Literal
+	}
+	return encodeStringAsUnicode(units).(*ast.StringLiteral).Literal
+}
+
+// 把顶层函数声明加入校验；检查尚未插入时先记下，插入时再加入函数列表
+func (c *selfDefendingCheck) include(fn *ast.FunctionLiteral) {
+	c.functions = append(c.functions, fn)
+	if c.list != nil {
+		c.list.Value = append(c.list.Value, &ast.Identifier{Name: fn.Name.Name})
+	}
+}
+
+func (c *selfDefendingCheck) includes(fn *ast.FunctionLiteral) bool {
+	for _, f := range c.functions {
+		if f == fn {
+			return true
+		}
+	}
+	return false
+}
+
+// 需要在生成的代码中定位的节点
+func (c *selfDefendingCheck) spans() map[ast.Node][2]int {
+	spans := make(map[ast.Node][2]int, len(c.functions)+1)
+	for _, fn := range c.functions {
+		spans[fn] = [2]int{-1, -1}
+	}
+	spans[c.expected] = [2]int{-1, -1}
+	return spans
+}
+
+// 按生成的代码计算校验值，填入期望值的占位处
+func (c *selfDefendingCheck) seal(code string, spans map[ast.Node][2]int) (string, error) {
+	hash := 5381
+	for _, fn := range c.functions {
+		span := spans[fn]
+		if span[0] < 0 {
+			return "", errors.New("自我保护检查的函数被之后的变换改动")
+		}
+		for _, unit := range utf16.Encode([]rune(code[span[0]:span[1]])) {
+			hash = (hash*33 + int(unit)) % 2147483647
+		}
+	}
+	span := spans[c.expected]
+	if span[0] < 0 || code[span[0]:span[1]] != selfDefendingPlaceholder {
+		return "", errors.New("自我保护检查的期望值被之后的变换改动")
+	}
+	return code[:span[0]] + "0x" + paddedHex(hash, 8) + code[span[1]:], nil
+}
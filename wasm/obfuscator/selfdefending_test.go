@@ -0,0 +1,114 @@
+package obfuscator
+
+import (
+	"context"
+	"os/exec"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 自我保护的输出与原始代码运行结果相同，与死代码注入等变换组合时也不会误判
+func TestSelfDefendingEquivalence(t *testing.T) {
+	node := requireNode(t)
+	src := strings.Join([]string{
+		"function tpl(x) { var s = \"if (x) { return y; }\"; var t = s.length + x; return t; }",
+		"function other(a, b) { var c = a * b; if (c > 10) { return c - 10; } return c; }",
+		"function third() { return 'third'; }",
+		"var fmt = function (v) { return '[' + v + ']'; };",
+		"console.log(tpl(1), other(3, 4), third(), fmt(tpl(2)));",
+	}, "\n")
+	configs := map[string]Config{
+		"selfDefending": {SelfDefending: true},
+		"deadCode":      {SelfDefending: true, DeadCodeInjection: true, DeadCodeInjectionThreshold: Threshold(1)},
+		"strings":       {SelfDefending: true, StringArray: true, StringEncryption: true, StringCipher: CipherRC4},
+		"all": {SelfDefending: true, IdentifierObfuscation: true, ControlFlowFlattening: true,
+			DeadCodeInjection: true, ExpressionDecomposition: true, StringEncryption: true, NumbersToExpressions: true},
+	}
+	checkEquivalent(t, node, src, configs, 6)
+}
+
+// 格式化、改动被校验的函数或删除检查语句后，代码不再正常运行
+func TestSelfDefendingTampering(t *testing.T) {
+	node := requireNode(t)
+	src := "function a(x) { return x + 1; }\nfunction b() { return a(1) * 2; }\nconsole.log(a(1), b());"
+	result, err := Obfuscate(src, Config{SelfDefending: true, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := runNode(t, node, result.Code)
+
+	check := regexp.MustCompile(`^var (\w+)=`).FindStringSubmatch(result.Code)
+	if check == nil {
+		t.Fatalf("没有找到检查语句:\n%s", result.Code)
+	}
+	guard := "if(" + check[1] + "){for(;;){}}"
+	tampered := map[string]string{
+		"formatted": strings.ReplaceAll(result.Code, ";", ";\n"),
+		"modified":  strings.Replace(result.Code, "return x+1;", "return x+2;", 1),
+		"deleted":   result.Code[strings.Index(result.Code, guard)+len(guard):],
+	}
+	// 字节解码函数总是参与校验
+	encrypted, err := Obfuscate(src+"console.log('secret');", Config{SelfDefending: true, StringEncryption: true, StringCipher: CipherRC4, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(encrypted.Code, "&65535") {
+		t.Fatalf("没有找到字节解码函数:\n%s", encrypted.Code)
+	}
+	decoder := strings.Replace(encrypted.Code, "&65535", "&0xffff", 1)
+	if output, ok := runTampered(node, decoder); ok && output == runNode(t, node, encrypted.Code) {
+		t.Errorf("decoder: 改动后仍然正常运行:\n%s", decoder)
+	}
+
+	for name, code := range tampered {
+		if code == result.Code {
+			t.Fatalf("%s: 代码没有改动:\n%s", name, result.Code)
+		}
+		if output, ok := runTampered(node, code); ok && output == want {
+			t.Errorf("%s: 改动后仍然正常运行:\n%s", name, code)
+		}
+	}
+}
+
+// 检查代码中的成员名不以明文出现，也不会被其他变换还原成明文
+func TestSelfDefendingHidesMemberNames(t *testing.T) {
+	node := requireNode(t)
+	src := "function add(a, b) { return a + b; }\nfunction twice(x) { return add(x, x); }\nconsole.log(add(1, 2), twice(4));"
+	configs := map[string]Config{
+		"selfDefending": {SelfDefending: true},
+		"members":       {SelfDefending: true, TransformMemberExpressions: true},
+		"stringArray":   {SelfDefending: true, TransformMemberExpressions: true, StringArray: true, StringArrayThreshold: Threshold(1)},
+		// 平坦化和死代码注入的不透明谓词本身会用到 arguments.length，不在这里组合
+		"combined": {SelfDefending: true, IdentifierObfuscation: true, ExpressionDecomposition: true,
+			NumbersToExpressions: true, DisguiseLiterals: true},
+	}
+	for name, config := range configs {
+		for seed := int64(1); seed <= 4; seed++ {
+			config.Seed = seed
+			result, err := Obfuscate(src, config)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			for _, member := range []string{"toString", "charCodeAt", "concat", "length"} {
+				if strings.Contains(result.Code, member) {
+					t.Errorf("%s/%d: 输出中出现了 %s:\n%s", name, seed, member, result.Code)
+				}
+			}
+			if got := runNode(t, node, result.Code); got != "3 8\n" {
+				t.Errorf("%s/%d: 运行结果 %q:\n%s", name, seed, got, result.Code)
+			}
+		}
+	}
+}
+
+// 运行改动过的代码，返回输出以及是否在时限内正常结束
+func runTampered(node, code string) (string, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, node)
+	cmd.Stdin = strings.NewReader(code)
+	output, err := cmd.Output()
+	return string(output), err == nil
+}
@@ -0,0 +1,267 @@
+package obfuscator
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+	"gopkg.in/sourcemap.v1/base64vlq"
+)
+
+// 未指定源文件名时 source map 中使用的名称
+const defaultSourceFileName = "input.js"
+
+// 一条映射：生成代码位置 -> 源码位置，行列均从 0 开始，列按 UTF-16 码元计算
+type mapping struct {
+	generatedLine   int
+	generatedColumn int
+	sourceLine      int
+	sourceColumn    int
+	name            int // names 中的下标，-1 表示没有名称
+}
+
+// Source Map v3 构建器
+//
+// 生成的代码来自模板字符串降级后的代码，映射的目标是降级前的原始源码。
+type sourceMapBuilder struct {
+	templates  *loweredTemplates
+	source     string // 原始源码
+	lineStarts []int  // 源码每行起始的字节偏移
+	names      []string
+	nameIndex  map[string]int
+	mappings   []mapping
+
+	// 生成代码中已扫描到的位置
+	scanned int
+	line    int
+	column  int
+}
+
+func newSourceMapBuilder(templates *loweredTemplates) *sourceMapBuilder {
+	source := templates.original
+	b := &sourceMapBuilder{
+		templates:  templates,
+		source:     source,
+		lineStarts: []int{0},
+		nameIndex:  make(map[string]int),
+	}
+	for i := 0; i < len(source); {
+		if n := lineTerminatorLength(source[i:]); n > 0 {
+			i += n
+			b.lineStarts = append(b.lineStarts, i)
+			continue
+		}
+		i++
+	}
+	return b
+}
+
+// 行终止符的字节长度，不是行终止符时返回 0
+func lineTerminatorLength(s string) int {
+	switch {
+	case strings.HasPrefix(s, "\r\n"):
+		return 2
+	case s[0] == '\n' || s[0] == '\r':
+		return 1
+	case strings.HasPrefix(s, "\u2028") || strings.HasPrefix(s, "\u2029"):
+		return 3
+	}
+	return 0
+}
+
+// 字符串的 UTF-16 长度
+func utf16Length(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// 源码偏移量对应的行列
+func (b *sourceMapBuilder) sourcePosition(offset int) (int, int) {
+	lo, hi := 0, len(b.lineStarts)-1
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if b.lineStarts[mid] <= offset {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return lo, utf16Length(b.source[b.lineStarts[lo]:offset])
+}
+
+// 推进到生成代码的末尾，更新当前行列
+func (b *sourceMapBuilder) advance(generated string) {
+	lineStart := b.scanned
+	for i := b.scanned; i < len(generated); {
+		if n := lineTerminatorLength(generated[i:]); n > 0 {
+			i += n
+			b.line++
+			b.column = 0
+			lineStart = i
+			continue
+		}
+		i++
+	}
+	b.column += utf16Length(generated[lineStart:])
+	b.scanned = len(generated)
+}
+
+// 在生成代码的当前末尾添加一条映射
+func (b *sourceMapBuilder) add(generated string, idx file.Idx, name string) {
+	offset := b.templates.originalOffset(int(idx) - 1)
+	if offset < 0 {
+		return
+	}
+	b.advance(generated)
+
+	m := mapping{generatedLine: b.line, generatedColumn: b.column, name: -1}
+	m.sourceLine, m.sourceColumn = b.sourcePosition(offset)
+	if name != "" {
+		i, ok := b.nameIndex[name]
+		if !ok {
+			i = len(b.names)
+			b.names = append(b.names, name)
+			b.nameIndex[name] = i
+		}
+		m.name = i
+	}
+
+	// 同一位置只保留第一条映射
+	if n := len(b.mappings); n > 0 {
+		last := b.mappings[n-1]
+		if last.generatedLine == m.generatedLine && last.generatedColumn == m.generatedColumn {
+			return
+		}
+	}
+	b.mappings = append(b.mappings, m)
+}
+
+// 源码中 idx 处的标识符原名
+func (b *sourceMapBuilder) identifierAt(idx file.Idx) string {
+	offset := b.templates.originalOffset(int(idx) - 1)
+	if offset < 0 || offset >= len(b.source) {
+		return ""
+	}
+	end := offset
+	for end < len(b.source) && isIdentifierByte(b.source[end]) {
+		end++
+	}
+	name := b.source[offset:end]
+	if name == "" || (name[0] >= '0' && name[0] <= '9') || !utf8.ValidString(name) {
+		return ""
+	}
+	return name
+}
+
+// 编码 mappings 字段
+func (b *sourceMapBuilder) encodeMappings() string {
+	var buf strings.Builder
+	enc := base64vlq.NewEncoder(&buf)
+	line, column := 0, 0
+	sourceLine, sourceColumn, name := 0, 0, 0
+	for i, m := range b.mappings {
+		if m.generatedLine != line {
+			for ; line < m.generatedLine; line++ {
+				buf.WriteByte(';')
+			}
+			column = 0
+		} else if i > 0 {
+			buf.WriteByte(',')
+		}
+		enc.Encode(m.generatedColumn - column)
+		enc.Encode(0) // 只有一个源文件
+		enc.Encode(m.sourceLine - sourceLine)
+		enc.Encode(m.sourceColumn - sourceColumn)
+		column, sourceLine, sourceColumn = m.generatedColumn, m.sourceLine, m.sourceColumn
+		if m.name >= 0 {
+			enc.Encode(m.name - name)
+			name = m.name
+		}
+	}
+	return buf.String()
+}
+
+// 输出 Source Map v3 JSON
+func (b *sourceMapBuilder) json(sourceName string) string {
+	if sourceName == "" {
+		sourceName = defaultSourceFileName
+	}
+	names := b.names
+	if names == nil {
+		names = []string{}
+	}
+	data, _ := json.Marshal(struct {
+		Version        int      `json:"version"`
+		Sources        []string `json:"sources"`
+		SourcesContent []string `json:"sourcesContent"`
+		Names          []string `json:"names"`
+		Mappings       string   `json:"mappings"`
+	}{
+		Version:        3,
+		Sources:        []string{sourceName},
+		SourcesContent: []string{b.source},
+		Names:          names,
+		Mappings:       b.encodeMappings(),
+	})
+	return string(data)
+}
+
+// 让替换节点继承原节点的位置，生成的代码仍然映射回原来的源码
+//
+// 只设置表达式最左侧的节点，它决定了整个表达式的起始位置。
+func inheritPosition(expr ast.Expression, idx file.Idx) {
+	for {
+		switch e := expr.(type) {
+		case *ast.BinaryExpression:
+			expr = e.Left
+		case *ast.CallExpression:
+			expr = e.Callee
+		case *ast.DotExpression:
+			expr = e.Left
+		case *ast.BracketExpression:
+			expr = e.Left
+		case *ast.AssignExpression:
+			expr = e.Left
+		case *ast.SequenceExpression:
+			if len(e.Sequence) == 0 {
+				return
+			}
+			expr = e.Sequence[0]
+		case *ast.UnaryExpression:
+			if e.Postfix {
+				expr = e.Operand
+			} else {
+				if e.Idx == 0 {
+					e.Idx = idx
+				}
+				return
+			}
+		case *ast.Identifier:
+			if e.Idx == 0 {
+				e.Idx = idx
+			}
+			return
+		case *ast.StringLiteral:
+			if e.Idx == 0 {
+				e.Idx = idx
+			}
+			return
+		case *ast.NumberLiteral:
+			if e.Idx == 0 {
+				e.Idx = idx
+			}
+			return
+		default:
+			return
+		}
+	}
+}
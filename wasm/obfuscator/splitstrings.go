@@ -0,0 +1,97 @@
+package obfuscator
+
+import (
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/token"
+)
+
+// 未指定 splitStringsChunkLength 时的分段长度
+const defaultSplitStringsChunkLength = 10
+
+func newSplitStringsTransform() Transform {
+	return &builtinTransform{
+		name:        "splitStrings",
+		description: "将较长的字符串拆成若干段再拼接",
+		options: []Option{
+			{Name: "splitStrings", Type: "boolean", Description: "启用字符串拆分"},
+			{Name: "splitStringsChunkLength", Type: "number", Description: "每段的长度（UTF-16 码元数），默认 10"},
+		},
+		enabled: func(cfg *Config) bool { return cfg.SplitStrings },
+		apply: func(ctx *Context) error {
+			splitStrings(ctx)
+			return nil
+		},
+	}
+}
+
+// 字符串拆分
+//
+// 超过分段长度的字符串拆成多段用 + 连接。拆分在字符串数组和字符串加密之前进行，
+// 每一段都会被单独处理，因此同一个字符串的各段可能使用不同的编码。
+// 指令序言保持原样；对象字面量的键不是表达式，不受影响。
+func splitStrings(ctx *Context) {
+	program := ctx.Program
+	size := ctx.Config.SplitStringsChunkLength
+	if size <= 0 {
+		size = defaultSplitStringsChunkLength
+	}
+	directives := collectDirectives(program)
+
+	walker := &astWalker{
+		leave: func(node ast.Node) ast.Node {
+			literal, ok := node.(*ast.StringLiteral)
+			if !ok || directives[literal] {
+				return node
+			}
+			units := stringUnits(literal)
+			if len(units) <= size {
+				return node
+			}
+			var result ast.Expression
+			for _, chunk := range splitUnits(units, size) {
+				part := unitsLiteral(chunk, escapeUnit)
+				if result == nil {
+					result = part
+				} else {
+					result = &ast.BinaryExpression{Operator: token.PLUS, Left: result, Right: part}
+				}
+			}
+			inheritPosition(result, literal.Idx)
+			return result
+		},
+	}
+	walker.walk(program)
+}
+
+// 按长度切分码元，不把代理对拆到两段中
+func splitUnits(units []uint16, size int) [][]uint16 {
+	var chunks [][]uint16
+	for len(units) > 0 {
+		n := size
+		if n >= len(units) {
+			n = len(units)
+		} else if isHighSurrogate(units[n-1]) && isLowSurrogate(units[n]) {
+			n++
+		}
+		chunks = append(chunks, units[:n])
+		units = units[n:]
+	}
+	return chunks
+}
+
+// 单个码元在字符串字面量中的写法：代理项用 \u 转义，其余与 quoteString 相同
+func escapeUnit(unit uint16) string {
+	if isHighSurrogate(unit) || isLowSurrogate(unit) {
+		return "\\u" + paddedHex(int(unit), 4)
+	}
+	quoted := quoteString(string(rune(unit)))
+	return quoted[1 : len(quoted)-1]
+}
+
+func isHighSurrogate(unit uint16) bool {
+	return unit >= 0xd800 && unit <= 0xdbff
+}
+
+func isLowSurrogate(unit uint16) bool {
+	return unit >= 0xdc00 && unit <= 0xdfff
+}
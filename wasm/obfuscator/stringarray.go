@@ -0,0 +1,171 @@
+package obfuscator
+
+import (
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/token"
+)
+
+func newStringArrayTransform() Transform {
+	return &builtinTransform{
+		name:        "stringArray",
+		description: "将字符串字面量移入打乱顺序的数组，通过访问函数按下标读取",
+		options: []Option{
+			{Name: "stringArray", Type: "boolean", Description: "启用字符串数组"},
+			{Name: "stringArrayThreshold", Type: "number", Description: "移入数组的字符串比例（0~1，未设置时为 1，0 表示不移入）"},
+			{Name: "stringArrayRotate", Type: "boolean", Description: "数组在运行时旋转还原"},
+			{Name: "stringArrayIndexOffset", Type: "number", Description: "访问函数参数相对数组下标的偏移量"},
+		},
+		enabled: func(cfg *Config) bool { return cfg.StringArray },
+		apply:   buildStringArray,
+	}
+}
+
+// 访问函数与旋转代码的模板；加密时数组中是密文，访问函数按调用点给出的密钥解密
+const (
+	stringArrayGetter          = `function __GET__(__I__) { return __ARRAY__[__I__ - __OFFSET__]; }`
+	stringArrayDecryptedGetter = `function __GET__(__I__, __K__) { return __DECODE__(__ARRAY__[__I__ - __OFFSET__], __K__); }`
+	stringArrayRotate          = `(function (__A__, __N__) { while (__N__--) { __A__.push(__A__.shift()); } })(__ARRAY__, __COUNT__);`
+)
+
+// 字符串数组
+//
+// 字符串按比例移入一个打乱顺序的数组，每处使用替换为访问函数调用。
+// 相同内容的字符串共用一个数组元素。启用旋转时数组以旋转后的顺序写出，
+// 启动时再旋转回来，静态看到的下标与元素对不上。
+//
+// 同时指定 stringCipher 时数组中保存的是各字符串的密文，密钥写在调用点，
+// 访问函数在读取时才解密，解密结果由解密函数缓存，未用到的字符串不会被解密。
+func buildStringArray(ctx *Context) error {
+	program, rng, config := ctx.Program, ctx.Rand, ctx.Config
+	threshold := thresholdValue(config.StringArrayThreshold, 1)
+	var runtime *stringRuntime
+	if config.StringEncryption && config.StringCipher != CipherNone {
+		var err error
+		if runtime, err = ctx.stringRuntime(); err != nil {
+			return err
+		}
+	}
+
+	arrayName, getterName := ctx.newName(), ctx.newName()
+	directives := collectDirectives(program)
+
+	// 每处使用先生成参数待定的调用，数组打乱后再填入下标
+	type use struct {
+		call  *ast.CallExpression
+		entry int
+	}
+	var entries []*ast.StringLiteral
+	var keys []string
+	var uses []use
+	index := make(map[string]int)
+	walker := &astWalker{
+		leave: func(node ast.Node) ast.Node {
+			literal, ok := node.(*ast.StringLiteral)
+			if !ok || directives[literal] || rng.Float64() >= threshold {
+				return node
+			}
+			// 按码元比较内容，Value 无法区分单独的代理项
+			units := stringUnits(literal)
+			content := string(unitBytes(units))
+			i, ok := index[content]
+			if !ok {
+				i = len(entries)
+				index[content] = i
+				if runtime != nil {
+					payload, key := runtime.keyed.seal(units)
+					entries = append(entries, &ast.StringLiteral{Value: payload})
+					keys = append(keys, key)
+				} else {
+					entries = append(entries, &ast.StringLiteral{Literal: literal.Literal, Value: literal.Value})
+				}
+			}
+			call := &ast.CallExpression{
+				Callee:       &ast.Identifier{Name: getterName},
+				ArgumentList: []ast.Expression{nil},
+			}
+			if runtime != nil {
+				key := &ast.StringLiteral{Value: keys[i]}
+				runtime.done[key] = true
+				call.ArgumentList = append(call.ArgumentList, key)
+			}
+			inheritPosition(call, literal.Idx)
+			uses = append(uses, use{call: call, entry: i})
+			return call
+		},
+	}
+	walker.walk(program)
+	if len(entries) == 0 {
+		return nil
+	}
+	if runtime != nil {
+		for _, entry := range entries {
+			runtime.done[entry] = true
+		}
+	}
+
+	// 打乱数组顺序
+	n := len(entries)
+	position := make([]int, n)
+	shuffled := make([]ast.Expression, n)
+	for i, j := range rng.Perm(n) {
+		shuffled[i] = entries[j]
+		position[j] = i
+	}
+	for _, u := range uses {
+		u.call.ArgumentList[0] = intLiteral(position[u.entry] + config.StringArrayIndexOffset)
+	}
+
+	// 向右旋转 rotation 位写出，运行时向左旋转同样的次数还原
+	rotation := 0
+	if config.StringArrayRotate && n > 1 {
+		rotation = 1 + rng.Intn(n-1)
+		shuffled = append(shuffled[n-rotation:], shuffled[:n-rotation]...)
+	}
+
+	stmts := []ast.Statement{
+		&ast.VariableStatement{List: []ast.Expression{
+			&ast.VariableExpression{Name: arrayName, Initializer: &ast.ArrayLiteral{Value: shuffled}},
+		}},
+	}
+	if rotation > 0 {
+		stmts = append(stmts, ctx.parseRuntime(stringArrayRotate, map[string]string{
+			"__A__":     ctx.newName(),
+			"__N__":     ctx.newName(),
+			"__ARRAY__": arrayName,
+			"__COUNT__": intToString(rotation),
+		})...)
+	}
+	getter, names := stringArrayGetter, map[string]string{
+		"__GET__":    getterName,
+		"__I__":      ctx.newName(),
+		"__ARRAY__":  arrayName,
+		"__OFFSET__": signedIntToString(config.StringArrayIndexOffset),
+	}
+	if runtime != nil {
+		getter = stringArrayDecryptedGetter
+		names["__K__"] = ctx.newName()
+		names["__DECODE__"] = runtime.keyed.decoderName()
+	}
+	stmts = append(stmts, ctx.parseRuntime(getter, names)...)
+	if runtime != nil {
+		stmts = append(stmts, runtime.emit()...)
+	}
+	prependStatements(program, stmts...)
+	return nil
+}
+
+// 整数表达式，负数输出为一元负号
+func intLiteral(value int) ast.Expression {
+	if value < 0 {
+		return &ast.UnaryExpression{Operator: token.MINUS, Operand: numberLiteral(-value)}
+	}
+	return numberLiteral(value)
+}
+
+// 带符号的整数转字符串
+func signedIntToString(n int) string {
+	if n < 0 {
+		return "-" + intToString(-n)
+	}
+	return intToString(n)
+}
@@ -0,0 +1,32 @@
+package obfuscator
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// 比例未设置时全部移入数组，0 时一个也不移入，1 时全部移入
+func TestStringArrayThreshold(t *testing.T) {
+	src := "console.log('alpha', 'beta', 'gamma', 'alpha');"
+	for seed := int64(1); seed <= 3; seed++ {
+		obfuscate := func(threshold *float64) string {
+			result, err := Obfuscate(src, Config{StringArray: true, StringArrayThreshold: threshold, CompactCode: true, Seed: seed})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return result.Code
+		}
+		unset, all, none := obfuscate(nil), obfuscate(Threshold(1)), obfuscate(Threshold(0))
+		if unset != all {
+			t.Errorf("seed %d: 未设置比例的结果与比例 1 不同:\n%s\n%s", seed, unset, all)
+		}
+		if got := strings.TrimSpace(none); got != "console.log('alpha','beta','gamma','alpha');" {
+			t.Errorf("seed %d: 比例 0 时仍然移入数组:\n%s", seed, none)
+		}
+		getter := `_0x\w+\(\d+\)`
+		if !regexp.MustCompile(`console\.log\(` + strings.Repeat(getter+",", 3) + getter + `\)`).MatchString(all) {
+			t.Errorf("seed %d: 比例 1 时没有全部移入数组:\n%s", seed, all)
+		}
+	}
+}
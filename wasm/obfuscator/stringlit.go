@@ -0,0 +1,145 @@
+package obfuscator
+
+import (
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/robertkrimen/otto/ast"
+)
+
+// 字符串字面量的 UTF-16 码元
+//
+// JavaScript 字符串是 UTF-16 码元序列。解析器给出的 Value 是 Go 字符串，
+// 无法表示单独的代理项（如 '\uD83D'），因此这里从源码中的原始写法重新解码转义，
+// 各种编码策略都以码元为单位处理。没有原始写法的节点（由变换生成）退回到 Value。
+func stringUnits(literal *ast.StringLiteral) []uint16 {
+	raw := literal.Literal
+	if len(raw) < 2 || (raw[0] != '\'' && raw[0] != '"') || raw[len(raw)-1] != raw[0] {
+		return utf16.Encode([]rune(literal.Value))
+	}
+	return decodeStringLiteral(raw[1 : len(raw)-1])
+}
+
+// 解码字符串字面量引号内的内容
+func decodeStringLiteral(body string) []uint16 {
+	units := make([]uint16, 0, len(body))
+	for i := 0; i < len(body); {
+		c := body[i]
+		if c != '\\' {
+			r, size := utf8.DecodeRuneInString(body[i:])
+			units = utf16.AppendRune(units, r)
+			i += size
+			continue
+		}
+
+		i++
+		if i >= len(body) {
+			break
+		}
+		c = body[i]
+		switch c {
+		case 'b':
+			units = append(units, '\b')
+		case 'f':
+			units = append(units, '\f')
+		case 'n':
+			units = append(units, '\n')
+		case 'r':
+			units = append(units, '\r')
+		case 't':
+			units = append(units, '\t')
+		case 'v':
+			units = append(units, '\v')
+		case 'x':
+			if value, ok := parseHex(body, i+1, 2); ok {
+				units = append(units, uint16(value))
+				i += 3
+				continue
+			}
+			units = append(units, 'x')
+		case 'u':
+			if value, ok := parseHex(body, i+1, 4); ok {
+				units = append(units, uint16(value))
+				i += 5
+				continue
+			}
+			units = append(units, 'u')
+		default:
+			if n := lineTerminatorLength(body[i:]); n > 0 {
+				// 续行：反斜杠加换行不产生任何字符
+				i += n
+				continue
+			}
+			if c >= '0' && c <= '7' {
+				// 旧式八进制转义，最多三位且不超过 \377
+				value, n := 0, 0
+				for n < 3 && i+n < len(body) && body[i+n] >= '0' && body[i+n] <= '7' {
+					next := value*8 + int(body[i+n]-'0')
+					if next > 0377 {
+						break
+					}
+					value = next
+					n++
+				}
+				units = append(units, uint16(value))
+				i += n
+				continue
+			}
+			// 其余字符的转义就是字符本身
+			r, size := utf8.DecodeRuneInString(body[i:])
+			units = utf16.AppendRune(units, r)
+			i += size
+			continue
+		}
+		i++
+	}
+	return units
+}
+
+// 解析固定位数的十六进制数
+func parseHex(s string, start, digits int) (int, bool) {
+	if start+digits > len(s) {
+		return 0, false
+	}
+	value := 0
+	for _, c := range []byte(s[start : start+digits]) {
+		var d int
+		switch {
+		case c >= '0' && c <= '9':
+			d = int(c - '0')
+		case c >= 'a' && c <= 'f':
+			d = int(c-'a') + 10
+		case c >= 'A' && c <= 'F':
+			d = int(c-'A') + 10
+		default:
+			return 0, false
+		}
+		value = value*16 + d
+	}
+	return value, true
+}
+
+// 码元序列对应的 Go 字符串，单独的代理项变为 U+FFFD
+func unitsToString(units []uint16) string {
+	return string(utf16.Decode(units))
+}
+
+// 由码元构造字符串字面量节点，escape 负责输出每个码元的写法
+func unitsLiteral(units []uint16, escape func(unit uint16) string) *ast.StringLiteral {
+	buf := make([]byte, 0, len(units)*6+2)
+	buf = append(buf, '\'')
+	for _, unit := range units {
+		buf = append(buf, escape(unit)...)
+	}
+	buf = append(buf, '\'')
+	return &ast.StringLiteral{Literal: string(buf), Value: unitsToString(units)}
+}
+
+// 固定位数的小写十六进制
+func paddedHex(value, digits int) string {
+	hex := intToHex(value)
+	for len(hex) < digits {
+		hex = "0" + hex
+	}
+	return hex
+}
@@ -0,0 +1,180 @@
+package obfuscator
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/robertkrimen/otto/ast"
+)
+
+// 测试用字符串：原始写法与期望的 UTF-16 码元
+var stringLiteralCases = []struct {
+	name  string
+	raw   string
+	units []uint16
+}{
+	{"ASCII", `'hello'`, utf16.Encode([]rune("hello"))},
+	{"CJK", `'中文字符串'`, utf16.Encode([]rune("中文字符串"))},
+	{"CJK escaped", `"\u4e2d\u6587"`, utf16.Encode([]rune("中文"))},
+	{"emoji", `'emoji 😀'`, utf16.Encode([]rune("emoji 😀"))},
+	{"emoji surrogate pair", `'😀'`, []uint16{0xd83d, 0xde00}},
+	{"lone high surrogate", `'a\uD83Db'`, []uint16{'a', 0xd83d, 'b'}},
+	{"lone low surrogate", `'\uDE00'`, []uint16{0xde00}},
+	{"hex escapes", `'\x41\xe9\xff'`, []uint16{'A', 0xe9, 0xff}},
+	{"single escapes", `'\b\f\n\r\t\v\'\"\\'`, []uint16{'\b', '\f', '\n', '\r', '\t', '\v', '\'', '"', '\\'}},
+	{"octal escapes", `'\0\101\400'`, []uint16{0, 'A', 040, '0'}},
+	{"line continuation", "'a\\\nb\\\r\nc'", []uint16{'a', 'b', 'c'}},
+	{"identity escape", `'\q\8'`, []uint16{'q', '8'}},
+}
+
+func TestStringUnits(t *testing.T) {
+	for _, tc := range stringLiteralCases {
+		got := stringUnits(&ast.StringLiteral{Literal: tc.raw})
+		if !equalUnits(got, tc.units) {
+			t.Errorf("%s: stringUnits(%s) = %x, want %x", tc.name, tc.raw, got, tc.units)
+		}
+	}
+}
+
+// 每种编码策略的输出在 JavaScript 中运行后都应还原出完全相同的码元
+//
+// otto 的字符串无法保存单独的代理项，这里用 node 运行，没有 node 时跳过。
+func TestStringEncodingRoundTrip(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("未找到 node")
+	}
+	configs := map[string]Config{
+		"encodings": {StringEncryption: true},
+		"rc4":       {StringEncryption: true, StringCipher: CipherRC4, ShuffleBase64Alphabet: true},
+		"xor":       {StringEncryption: true, StringCipher: CipherXOR},
+		"array":     {StringArray: true, StringArrayRotate: true, StringEncryption: true},
+		"array rc4": {StringArray: true, StringArrayIndexOffset: -3, StringEncryption: true, StringCipher: CipherRC4},
+		"array xor": {StringArray: true, StringArrayThreshold: Threshold(0.5), StringEncryption: true, StringCipher: CipherXOR},
+	}
+
+	var src strings.Builder
+	src.WriteString("var result = [];\n")
+	for _, tc := range stringLiteralCases {
+		src.WriteString("result.push(" + tc.raw + ");\n")
+	}
+
+	for name, cfg := range configs {
+		// 不同种子覆盖不同的随机策略
+		for seed := int64(1); seed <= 20; seed++ {
+			cfg.Seed = seed
+			result, err := Obfuscate(src.String(), cfg)
+			if err != nil {
+				t.Fatalf("%s seed %d: %v", name, seed, err)
+			}
+			for i, got := range evalStrings(t, node, result.Code) {
+				if want := stringLiteralCases[i].units; !equalUnits(got, want) {
+					t.Errorf("%s seed %d %s: got %x, want %x", name, seed, stringLiteralCases[i].name, got, want)
+				}
+			}
+		}
+	}
+}
+
+// 运行代码，读取 result 数组中每个字符串的码元
+func evalStrings(t *testing.T, node, code string) [][]uint16 {
+	t.Helper()
+	cmd := exec.Command(node)
+	cmd.Stdin = strings.NewReader(code + `
+		var codes = [];
+		for (var i = 0; i < result.length; i++) {
+			var units = [];
+			for (var j = 0; j < result[i].length; j++) {
+				units.push(result[i].charCodeAt(j));
+			}
+			codes.push(units.join(','));
+		}
+		process.stdout.write(codes.join(';'));
+	`)
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("运行失败: %v\n%s", err, code)
+	}
+	var strs [][]uint16
+	for _, item := range strings.Split(string(output), ";") {
+		var units []uint16
+		if item != "" {
+			for _, code := range strings.Split(item, ",") {
+				n, _ := strconv.Atoi(code)
+				units = append(units, uint16(n))
+			}
+		}
+		strs = append(strs, units)
+	}
+	return strs
+}
+
+func equalUnits(a, b []uint16) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// 加密后的载荷按 Base64 解码、解密后应得到原始码元
+func TestCipherPayload(t *testing.T) {
+	ciphers := map[string]func([]byte, string){
+		CipherRC4: rc4Crypt,
+		CipherXOR: func(data []byte, key string) {
+			// 滚动异或的逆运算：前一个字节取密文
+			prev := byte(len(key))
+			for n := range data {
+				c := data[n]
+				data[n] ^= key[n%len(key)] ^ prev
+				prev = c
+			}
+		},
+	}
+	for name, decrypt := range ciphers {
+		for _, tc := range stringLiteralCases {
+			data := unitBytes(tc.units)
+			switch name {
+			case CipherRC4:
+				rc4Crypt(data, "k3y")
+			case CipherXOR:
+				rollingXOR(data, "k3y")
+			}
+			payload := base64Decode(base64Encode(data, standardBase64Alphabet), standardBase64Alphabet)
+			decrypt(payload, "k3y")
+			var got []uint16
+			for i := 0; i+1 < len(payload); i += 2 {
+				got = append(got, uint16(payload[i])<<8|uint16(payload[i+1]))
+			}
+			if !equalUnits(got, tc.units) {
+				t.Errorf("%s %s: got %x, want %x", name, tc.name, got, tc.units)
+			}
+		}
+	}
+}
+
+// 与运行时字节解码函数相同的逻辑
+func base64Decode(s, alphabet string) []byte {
+	var data []byte
+	buffer, bits := 0, 0
+	for i := 0; i < len(s); i++ {
+		c := strings.IndexByte(alphabet, s[i])
+		if c < 0 {
+			continue
+		}
+		buffer = (buffer<<6 | c) & 0xffff
+		bits += 6
+		if bits >= 8 {
+			bits -= 8
+			data = append(data, byte(buffer>>bits))
+		}
+	}
+	return data
+}
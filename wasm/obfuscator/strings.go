@@ -0,0 +1,252 @@
+package obfuscator
+
+import (
+	"math/rand"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/token"
+)
+
+func newStringTransform() Transform {
+	return &builtinTransform{
+		name:        "strings",
+		description: "将字符串字面量替换为编码后的等价表达式",
+		options: []Option{
+			{Name: "stringEncryption", Type: "boolean", Description: "启用字符串加密"},
+			{Name: "stringCipher", Type: "string", Description: "加密算法：rc4、xor，留空时只做编码"},
+			{Name: "shuffleBase64Alphabet", Type: "boolean", Description: "每次构建随机打乱 Base64 字母表"},
+		},
+		enabled: func(cfg *Config) bool { return cfg.StringEncryption },
+		apply:   encryptStrings,
+	}
+}
+
+// 字符串变换共用的运行时
+//
+// 字符串数组和字符串加密使用同一套解码函数，每个函数只插入一次。
+// 已经处理过的字面量（数组中的密文、调用点的密钥以及运行时代码中的字符串）记录在 done 中，
+// 之后的变换不再改写它们。
+type stringRuntime struct {
+	base64 *base64Runtime
+	keyed  *keyedEncryptor // 未指定 stringCipher 时为 nil
+	done   map[*ast.StringLiteral]bool
+}
+
+// 本次混淆的字符串运行时，首次调用时创建
+func (ctx *Context) stringRuntime() (*stringRuntime, error) {
+	if ctx.strings != nil {
+		return ctx.strings, nil
+	}
+	r := &stringRuntime{base64: newBase64Runtime(ctx), done: make(map[*ast.StringLiteral]bool)}
+	if ctx.Config.StringEncryption && ctx.Config.StringCipher != CipherNone {
+		keyed, err := newKeyedEncryptor(ctx, r.base64)
+		if err != nil {
+			return nil, err
+		}
+		r.keyed = keyed
+	}
+	ctx.strings = r
+	return r, nil
+}
+
+// 尚未插入的解码函数，其中的字符串记为已处理
+func (r *stringRuntime) emit() []ast.Statement {
+	stmts := r.base64.runtime()
+	if r.keyed != nil {
+		stmts = append(stmts, r.keyed.runtime()...)
+	}
+	walker := &astWalker{
+		enter: func(node ast.Node) bool {
+			if literal, ok := node.(*ast.StringLiteral); ok {
+				r.done[literal] = true
+			}
+			return true
+		},
+	}
+	for _, stmt := range stmts {
+		walker.walk(stmt)
+	}
+	return stmts
+}
+
+// 字符串加密
+//
+// 指定 stringCipher 时使用带密钥的加密和运行时解密函数，否则随机选用一种编码。
+func encryptStrings(ctx *Context) error {
+	program, rng := ctx.Program, ctx.Rand
+	runtime, err := ctx.stringRuntime()
+	if err != nil {
+		return err
+	}
+	base64, keyed := runtime.base64, runtime.keyed
+
+	// 指令序言（如 "use strict"）必须保持原样
+	directives := collectDirectives(program)
+
+	walker := &astWalker{
+		leave: func(node ast.Node) ast.Node {
+			literal, ok := node.(*ast.StringLiteral)
+			if !ok || directives[literal] || runtime.done[literal] {
+				return node
+			}
+			var encrypted ast.Expression
+			if keyed != nil {
+				units := stringUnits(literal)
+				if len(units) == 0 {
+					return node
+				}
+				encrypted = keyed.encrypt(units)
+			} else {
+				encrypted = encryptString(literal, rng, base64)
+			}
+			inheritPosition(encrypted, literal.Idx)
+			return encrypted
+		},
+	}
+	walker.walk(program)
+
+	prependStatements(program, runtime.emit()...)
+	return nil
+}
+
+// 收集程序和函数体开头的指令字符串
+func collectDirectives(program *ast.Program) map[*ast.StringLiteral]bool {
+	directives := make(map[*ast.StringLiteral]bool)
+	mark := func(body []ast.Statement) {
+		prologue, _ := splitDirectives(body)
+		for _, stmt := range prologue {
+			directives[stmt.(*ast.ExpressionStatement).Expression.(*ast.StringLiteral)] = true
+		}
+	}
+	mark(program.Body)
+	walker := &astWalker{
+		enter: func(node ast.Node) bool {
+			if fn, ok := node.(*ast.FunctionLiteral); ok {
+				if body, ok := fn.Body.(*ast.BlockStatement); ok {
+					mark(body.List)
+				}
+			}
+			return true
+		},
+	}
+	walker.walk(program)
+	return directives
+}
+
+// 加密单个字符串
+func encryptString(literal *ast.StringLiteral, rng *rand.Rand, base64 *base64Runtime) ast.Expression {
+	units := stringUnits(literal)
+
+	// 跳过空字符串和很短的字符串
+	if len(units) <= 1 {
+		return literal
+	}
+
+	// 选择加密策略，优先使用更兼容的方法
+	strategy := rng.Intn(5)
+
+	switch strategy {
+	case 0:
+		// 字符编码 - 最兼容
+		return encodeStringAsCharCodes(units)
+	case 1:
+		// 十六进制编码 - 兼容性好
+		return encodeStringAsHex(units)
+	case 2:
+		// Unicode 编码
+		return encodeStringAsUnicode(units)
+	case 3:
+		// 分组字符编码
+		return encodeStringAsCharGroups(units, rng)
+	case 4:
+		// Base64 编码，所有调用共用一个解码函数
+		return base64.encodeString(units)
+	default:
+		return literal
+	}
+}
+
+// 字符编码加密：每个码元一个 String.fromCharCode 调用
+func encodeStringAsCharCodes(units []uint16) ast.Expression {
+	var result ast.Expression
+	for _, unit := range units {
+		part := fromCharCodeCall(int(unit))
+		if result == nil {
+			result = part
+		} else {
+			result = &ast.BinaryExpression{Operator: token.PLUS, Left: result, Right: part}
+		}
+	}
+	return result
+}
+
+// String.fromCharCode(...) 调用
+func fromCharCodeCall(codes ...int) ast.Expression {
+	args := make([]ast.Expression, len(codes))
+	for i, code := range codes {
+		args[i] = numberLiteral(code)
+	}
+	return &ast.CallExpression{
+		Callee: &ast.DotExpression{
+			Left:       &ast.Identifier{Name: "String"},
+			Identifier: &ast.Identifier{Name: "fromCharCode"},
+		},
+		ArgumentList: args,
+	}
+}
+
+// 十六进制编码加密：0xFF 以内用 \x，其余用 \u
+func encodeStringAsHex(units []uint16) ast.Expression {
+	return unitsLiteral(units, func(unit uint16) string {
+		if unit <= 0xff {
+			return "\\x" + paddedHex(int(unit), 2)
+		}
+		return "\\u" + paddedHex(int(unit), 4)
+	})
+}
+
+// Unicode 编码加密：每个码元一个 \u，代理对自然拆成两个转义
+func encodeStringAsUnicode(units []uint16) ast.Expression {
+	return unitsLiteral(units, func(unit uint16) string {
+		return "\\u" + paddedHex(int(unit), 4)
+	})
+}
+
+// 分组字符编码：随机长度的码元分组，每组一个 String.fromCharCode 调用
+func encodeStringAsCharGroups(units []uint16, rng *rand.Rand) ast.Expression {
+	var result ast.Expression
+	for len(units) > 0 {
+		n := 1 + rng.Intn(4)
+		if n > len(units) {
+			n = len(units)
+		}
+		codes := make([]int, n)
+		for i, unit := range units[:n] {
+			codes[i] = int(unit)
+		}
+		units = units[n:]
+
+		part := fromCharCodeCall(codes...)
+		if result == nil {
+			result = part
+		} else {
+			result = &ast.BinaryExpression{Operator: token.PLUS, Left: result, Right: part}
+		}
+	}
+	return result
+}
+
+// 简单的整数转十六进制函数
+func intToHex(n int) string {
+	if n == 0 {
+		return "0"
+	}
+
+	hexChars := "0123456789abcdef"
+	var result []byte
+	for n > 0 {
+		result = append([]byte{hexChars[n%16]}, result...)
+		n /= 16
+	}
+	return string(result)
+}
@@ -0,0 +1,517 @@
+package obfuscator
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+)
+
+// 模板字符串降级
+//
+// 解析器只支持 ES5，模板字符串在解析之前改写为等价的 ES5 代码：
+//
+//	`a${x}b`      →   "a".concat((x), "b")
+//	tag`a${x}b`   →   tag(t0 || (t0 = helper(["a", "b"], ["a", "b"])), (x))
+//
+// 静态部分成为普通的字符串字面量，之后与其他字符串一样被拆分、移入数组和加密；
+// 嵌入的表达式原样保留（其中的模板字符串递归改写），随后参与全部变换。
+// concat 与模板字符串一样用 ToString 转换每个部分。
+// 标签模板的字符串数组带有 raw 属性，两者都被冻结并按调用点缓存，同一调用点每次得到同一个对象；
+// 无效的转义在 cooked 数组中为 undefined，raw 中保留原文。
+// 模板中的换行写成转义加续行，降级后的代码行号不变。
+type loweredTemplates struct {
+	code     string // 降级后的代码
+	original string // 原始源码
+	helper   string // 构造标签模板字符串数组的函数名，没有标签模板时为空
+	caches   []string
+	segments []templateSegment
+	file     *file.File // 原始源码，用于计算行列
+}
+
+// 降级前后源码位置的对应：降级后从 lowered 开始的一段对应原始源码中从 original 开始的一段；
+// generated 为真时这一段是生成的代码，整体对应所在模板字符串的开头
+type templateSegment struct {
+	lowered   int
+	original  int
+	generated bool
+}
+
+// 构造标签模板字符串数组的运行时函数
+const taggedTemplateHelper = `
+function __HELPER__(__COOKED__, __RAW__) {
+	return Object.freeze(Object.defineProperty(__COOKED__, 'raw', { value: Object.freeze(__RAW__) }));
+}`
+
+// 改写源码中的模板字符串；没有模板字符串或无法改写（如模板没有结束）时返回原样的源码，
+// 交给解析器报告错误
+func lowerTemplates(src string) *loweredTemplates {
+	unchanged := &loweredTemplates{code: src, original: src}
+	if !strings.Contains(src, "`") {
+		return unchanged
+	}
+	l := &templateLowerer{src: src, helper: unusedName(src, "__template")}
+	out := l.code(false)
+	if l.failed || l.pos < len(src) {
+		return unchanged
+	}
+	lowered := &loweredTemplates{
+		code:     out.buf.String(),
+		original: src,
+		caches:   l.caches,
+		segments: out.segments,
+	}
+	if len(l.caches) > 0 {
+		lowered.helper = l.helper
+	}
+	return lowered
+}
+
+// 降级后代码中的偏移量对应的原始源码偏移量，超出降级后代码范围时返回 -1
+func (t *loweredTemplates) originalOffset(offset int) int {
+	if offset < 0 || offset > len(t.code) {
+		return -1
+	}
+	if len(t.segments) == 0 {
+		return offset
+	}
+	i := sort.Search(len(t.segments), func(i int) bool { return t.segments[i].lowered > offset }) - 1
+	if i < 0 {
+		return offset
+	}
+	s := t.segments[i]
+	if s.generated {
+		return s.original
+	}
+	return s.original + offset - s.lowered
+}
+
+// 降级后代码中的位置在原始源码中的行列，超出源码范围时返回 nil
+func (t *loweredTemplates) position(idx file.Idx) *file.Position {
+	offset := t.originalOffset(int(idx) - 1)
+	if offset < 0 {
+		return nil
+	}
+	if t.file == nil {
+		t.file = file.NewFile("", t.original, 1)
+	}
+	return t.file.Position(file.Idx(offset + 1))
+}
+
+// 标签模板用到的辅助函数和缓存变量，在解析之后插入到程序开头
+//
+// 降级时用的是源码中没有出现过的临时名称，这里统一换成 newName 分配的名称。
+func (t *loweredTemplates) runtime(ctx *Context) []ast.Statement {
+	if t.helper == "" {
+		return nil
+	}
+	names := map[string]string{t.helper: ctx.newName()}
+	caches := make([]ast.Expression, len(t.caches))
+	for i, name := range t.caches {
+		names[name] = ctx.newName()
+		caches[i] = &ast.VariableExpression{Name: names[name]}
+	}
+	walker := &astWalker{
+		enter: func(node ast.Node) bool {
+			if id, ok := node.(*ast.Identifier); ok && names[id.Name] != "" {
+				id.Name = names[id.Name]
+			}
+			return true
+		},
+	}
+	walker.walk(ctx.Program)
+
+	stmts := []ast.Statement{&ast.VariableStatement{List: caches}}
+	return append(stmts, ctx.parseRuntime(taggedTemplateHelper, map[string]string{
+		"__HELPER__": names[t.helper],
+		"__COOKED__": ctx.newName(),
+		"__RAW__":    ctx.newName(),
+	})...)
+}
+
+// 源码中没有出现过的名称（不是任何标识符的一部分），加上序号后仍然唯一
+func unusedName(src, base string) string {
+	for strings.Contains(src, base) {
+		base = "_" + base
+	}
+	return base
+}
+
+// 降级后的代码片段及其位置对应
+type loweredText struct {
+	buf      strings.Builder
+	segments []templateSegment
+}
+
+// 原样复制源码中从 original 开始的一段
+func (t *loweredText) copy(s string, original int) {
+	if s == "" {
+		return
+	}
+	lowered := t.buf.Len()
+	if n := len(t.segments); n > 0 {
+		last := t.segments[n-1]
+		if !last.generated && last.original+lowered-last.lowered == original {
+			t.buf.WriteString(s)
+			return
+		}
+	}
+	t.segments = append(t.segments, templateSegment{lowered: lowered, original: original})
+	t.buf.WriteString(s)
+}
+
+// 写入生成的代码，整体对应源码中的 original 处
+func (t *loweredText) generate(s string, original int) {
+	if s == "" {
+		return
+	}
+	t.segments = append(t.segments, templateSegment{lowered: t.buf.Len(), original: original, generated: true})
+	t.buf.WriteString(s)
+}
+
+// 追加另一段降级后的代码
+func (t *loweredText) append(other *loweredText) {
+	offset := t.buf.Len()
+	for _, s := range other.segments {
+		s.lowered += offset
+		t.segments = append(t.segments, s)
+	}
+	t.buf.WriteString(other.buf.String())
+}
+
+// 模板字符串扫描器
+//
+// 只区分降级需要的词法单元：字符串、注释、正则表达式、模板和花括号，其余原样复制。
+// 斜杠是除号还是正则表达式的开头由前一个有意义的词法单元判断。
+type templateLowerer struct {
+	src    string
+	pos    int
+	helper string
+	caches []string
+	failed bool
+}
+
+// 之后的斜杠开始正则表达式的关键字
+var regexAfterKeywords = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "new": true,
+	"delete": true, "void": true, "throw": true, "case": true, "do": true, "else": true,
+}
+
+// 扫描代码直到源码结束；substitution 为真时扫描 ${} 中的表达式，遇到对应的右花括号时结束
+func (l *templateLowerer) code(substitution bool) *loweredText {
+	out := &loweredText{}
+	src := l.src
+	regexAllowed := true
+	depth := 0
+	for l.pos < len(src) {
+		start := l.pos
+		c := src[l.pos]
+		switch {
+		case c == '`':
+			l.pos++
+			l.template(out, start, !regexAllowed)
+			if l.failed {
+				return out
+			}
+			regexAllowed = false
+			continue
+		case c == '\'' || c == '"':
+			l.skipQuoted(c)
+			regexAllowed = false
+		case c == '/' && strings.HasPrefix(src[l.pos:], "//"):
+			for l.pos < len(src) && src[l.pos] != '\n' && src[l.pos] != '\r' {
+				l.pos++
+			}
+		case c == '/' && strings.HasPrefix(src[l.pos:], "/*"):
+			if end := strings.Index(src[l.pos+2:], "*/"); end >= 0 {
+				l.pos += end + 4
+			} else {
+				l.pos = len(src)
+			}
+		case c == '/' && regexAllowed:
+			l.skipRegExp()
+			regexAllowed = false
+		case isIdentifierByte(c):
+			for l.pos < len(src) && (isIdentifierByte(src[l.pos]) || c >= '0' && c <= '9' && src[l.pos] == '.') {
+				l.pos++
+			}
+			regexAllowed = regexAfterKeywords[src[start:l.pos]]
+		case c == '{':
+			depth++
+			l.pos++
+			regexAllowed = true
+		case c == '}':
+			if substitution && depth == 0 {
+				l.pos++
+				return out
+			}
+			depth--
+			l.pos++
+			regexAllowed = true
+		case c == ')' || c == ']':
+			l.pos++
+			regexAllowed = false
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f':
+			l.pos++
+		case strings.HasPrefix(src[l.pos:], "++") || strings.HasPrefix(src[l.pos:], "--"):
+			l.pos += 2
+			regexAllowed = false
+		default:
+			l.pos++
+			regexAllowed = true
+		}
+		out.copy(src[start:l.pos], start)
+	}
+	if substitution {
+		l.failed = true
+	}
+	return out
+}
+
+// 跳过字符串字面量
+func (l *templateLowerer) skipQuoted(quote byte) {
+	for l.pos++; l.pos < len(l.src); l.pos++ {
+		switch l.src[l.pos] {
+		case '\\':
+			l.pos++
+		case quote, '\n', '\r':
+			l.pos++
+			return
+		}
+	}
+}
+
+// 跳过正则表达式字面量及其标志
+func (l *templateLowerer) skipRegExp() {
+	class := false
+	for l.pos++; l.pos < len(l.src); l.pos++ {
+		switch c := l.src[l.pos]; {
+		case c == '\\':
+			l.pos++
+		case c == '[':
+			class = true
+		case c == ']':
+			class = false
+		case c == '/' && !class:
+			l.pos++
+			for l.pos < len(l.src) && isIdentifierByte(l.src[l.pos]) {
+				l.pos++
+			}
+			return
+		case c == '\n' || c == '\r':
+			return
+		}
+	}
+}
+
+// 改写一个模板字符串，start 是反引号的位置，扫描从反引号之后开始；
+// tagged 为真时前面是标签表达式，生成的参数列表紧跟在它后面
+func (l *templateLowerer) template(out *loweredText, start int, tagged bool) {
+	src := l.src
+	var quasis []string
+	var substitutions []*loweredText
+	quasiStart := l.pos
+	for {
+		if l.pos >= len(src) {
+			l.failed = true
+			return
+		}
+		switch c := src[l.pos]; {
+		case c == '\\':
+			l.pos += 2
+			continue
+		case c == '`':
+			quasis = append(quasis, src[quasiStart:l.pos])
+			l.pos++
+		case c == '$' && strings.HasPrefix(src[l.pos:], "${"):
+			quasis = append(quasis, src[quasiStart:l.pos])
+			l.pos += 2
+			substitution := l.code(true)
+			if l.failed {
+				return
+			}
+			substitutions = append(substitutions, substitution)
+			quasiStart = l.pos
+			continue
+		default:
+			l.pos++
+			continue
+		}
+		break
+	}
+
+	if !tagged {
+		// 未加标签的模板中出现无效转义是语法错误，留给解析器报告
+		first, ok := cookTemplate(quasis[0])
+		if !ok {
+			l.failed = true
+			return
+		}
+		out.generate(first, start)
+		if len(substitutions) == 0 {
+			return
+		}
+		out.generate(".concat(", start)
+		for i, substitution := range substitutions {
+			if i > 0 {
+				out.generate(", ", start)
+			}
+			out.generate("(", start)
+			out.append(substitution)
+			out.generate(")", start)
+			if quasis[i+1] == "" {
+				continue
+			}
+			quasi, ok := cookTemplate(quasis[i+1])
+			if !ok {
+				l.failed = true
+				return
+			}
+			out.generate(", "+quasi, start)
+		}
+		out.generate(")", start)
+		return
+	}
+
+	cache := l.helper + strconv.Itoa(len(l.caches))
+	l.caches = append(l.caches, cache)
+	cooked := make([]string, len(quasis))
+	raw := make([]string, len(quasis))
+	for i, quasi := range quasis {
+		var ok bool
+		if cooked[i], ok = cookTemplate(quasi); !ok {
+			cooked[i] = "void 0"
+		}
+		// cooked 中的换行已经保持了行号，raw 只用转义
+		raw[i] = quoteTemplateRaw(quasi, !ok)
+	}
+	out.generate("("+cache+" || ("+cache+" = "+l.helper+"(["+strings.Join(cooked, ", ")+"], ["+strings.Join(raw, ", ")+"]))", start)
+	for _, substitution := range substitutions {
+		out.generate(", (", start)
+		out.append(substitution)
+		out.generate(")", start)
+	}
+	out.generate(")", start)
+}
+
+// 模板的一个静态部分按模板的转义规则求值，写成等值的双引号字符串字面量；有无效转义时返回 false
+//
+// 模板特有的转义（\`、\$、\u{...}）改写为 ES5 的写法，换行统一为 \n 并以续行结束，保持行号不变。
+func cookTemplate(quasi string) (string, bool) {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(quasi); {
+		c := quasi[i]
+		switch {
+		case c == '\\':
+			if i+1 >= len(quasi) {
+				return "", false
+			}
+			next := quasi[i+1]
+			switch {
+			case next == '`' || next == '$' || next == '{' || next == '}':
+				b.WriteByte(next)
+				i += 2
+			case next == 'u' && i+2 < len(quasi) && quasi[i+2] == '{':
+				end := strings.IndexByte(quasi[i+3:], '}')
+				if end < 1 {
+					return "", false
+				}
+				value, err := strconv.ParseUint(quasi[i+3:i+3+end], 16, 32)
+				if err != nil || value > 0x10ffff {
+					return "", false
+				}
+				for _, unit := range utf16.Encode([]rune{rune(value)}) {
+					b.WriteString(`\u` + paddedHex(int(unit), 4))
+				}
+				i += 4 + end
+			case next == 'u':
+				if _, ok := parseHex(quasi, i+2, 4); !ok {
+					return "", false
+				}
+				b.WriteString(quasi[i : i+6])
+				i += 6
+			case next == 'x':
+				if _, ok := parseHex(quasi, i+2, 2); !ok {
+					return "", false
+				}
+				b.WriteString(quasi[i : i+4])
+				i += 4
+			case next == '0':
+				// 模板中不允许八进制转义，\0 后面不能紧跟数字
+				if i+2 < len(quasi) && quasi[i+2] >= '0' && quasi[i+2] <= '9' {
+					return "", false
+				}
+				b.WriteString(`\0`)
+				i += 2
+			case next >= '1' && next <= '9':
+				return "", false
+			default:
+				if n := lineTerminatorLength(quasi[i+1:]); n > 0 {
+					// 续行
+					b.WriteString(quasi[i : i+1+n])
+					i += 1 + n
+					continue
+				}
+				b.WriteByte(c)
+				b.WriteByte(next)
+				i += 2
+			}
+		case c == '"':
+			b.WriteString(`\"`)
+			i++
+		case c == '\r' || c == '\n':
+			// 模板中的 \r\n 和 \r 都视为 \n
+			i += lineTerminatorLength(quasi[i:])
+			b.WriteString("\\n\\\n")
+		case strings.HasPrefix(quasi[i:], "\u2028"):
+			b.WriteString(`\u2028`)
+			i += 3
+		case strings.HasPrefix(quasi[i:], "\u2029"):
+			b.WriteString(`\u2029`)
+			i += 3
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	b.WriteByte('"')
+	return b.String(), true
+}
+
+// 模板静态部分的原文（raw），写成双引号字符串字面量；keepLines 为真时换行以续行结束，保持行号不变
+func quoteTemplateRaw(quasi string, keepLines bool) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(quasi); {
+		c := quasi[i]
+		switch {
+		case c == '\\':
+			b.WriteString(`\\`)
+			i++
+		case c == '"':
+			b.WriteString(`\"`)
+			i++
+		case c == '\r' || c == '\n':
+			i += lineTerminatorLength(quasi[i:])
+			b.WriteString(`\n`)
+			if keepLines {
+				b.WriteString("\\\n")
+			}
+		case strings.HasPrefix(quasi[i:], "\u2028"):
+			b.WriteString(`\u2028`)
+			i += 3
+		case strings.HasPrefix(quasi[i:], "\u2029"):
+			b.WriteString(`\u2029`)
+			i += 3
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
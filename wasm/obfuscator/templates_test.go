@@ -0,0 +1,52 @@
+package obfuscator
+
+import (
+	"strings"
+	"testing"
+)
+
+// 模板字符串降级后的运行结果与原始代码相同，字符串变换照常作用于模板的静态部分
+func TestTemplateLowering(t *testing.T) {
+	node := requireNode(t)
+	src := strings.Join([]string{
+		"var a = 1, b = { c: 'x/y' };",
+		"function show(v) { console.log(JSON.stringify(v)); }",
+		"function tag(strings) { return [strings, strings.raw, Object.isFrozen(strings), [].slice.call(arguments, 1)]; }",
+		"function same() { return (function (s) { return s; })`x`; }",
+		"show(`plain`);",
+		"show(`a${a}b${`nested ${b.c}`}c${a + 1}`);",
+		"show(`${a}${a}`);",
+		"show(`line 1",
+		"line 2\\n\\`quoted\\` \\${x} \"double\" 'single' \\u{1F600} \\x41 \\0`);",
+		"show(`obj ${ { k: '}' }.k } ${ function () { return '`'; }() }`);",
+		"show(tag`x${a}y${b.c}z`);",
+		"show(tag`\\unicode and \\u{g} ${a}`);",
+		"show(String.raw`C:\\path\\${a}\\n`);",
+		"show(same() === same());",
+		"var r = a / 2 / `1`.length, re = /`/g.test('`');",
+		"show([r, re]);",
+	}, "\n")
+	configs := map[string]Config{
+		"default": {},
+		"strings": {StringEncryption: true, StringCipher: CipherRC4},
+		"array":   {StringArray: true, SplitStrings: true, SplitStringsChunkLength: 2},
+		"all":     {IdentifierObfuscation: true, TransformMemberExpressions: true, ControlFlowFlattening: true, CompactCode: true},
+	}
+	checkEquivalent(t, node, src, configs, 3)
+}
+
+// 降级后的错误位置与改名映射位置都换算回原始源码
+func TestTemplateLoweringPositions(t *testing.T) {
+	if ok, errs := Validate("var t = `${a}` + `b`; var u = a ?? b;"); ok || !strings.HasPrefix(errs[0], "Line 1:33 不支持空值合并 ??") {
+		t.Errorf("Validate = %v", errs)
+	}
+	result, err := Obfuscate("var t = `${1}`;\nvar s = `${2}`, u = 3;", Config{IdentifierObfuscation: true, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range result.RenameMap {
+		if entry.Original == "u" && (entry.Line != 2 || entry.Column != 17) {
+			t.Errorf("u 的位置为 %d:%d，期望 2:17", entry.Line, entry.Column)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package obfuscator
+
+import (
+	"errors"
+	"math/rand"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+)
+
+// Transform 混淆变换
+//
+// 每个变换是一次对 AST 的改写，由 Registry 按顺序执行。
+type Transform interface {
+	// Name 变换的唯一名称
+	Name() string
+	// Description 变换的简要说明
+	Description() string
+	// Options 变换读取的配置项
+	Options() []Option
+	// Enabled 根据配置判断本次是否需要执行
+	Enabled(cfg *Config) bool
+	// Apply 改写 AST
+	Apply(ctx *Context) error
+}
+
+// Option 配置项说明，Name 对应 Config 中的 JSON 字段名
+type Option struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+// Context 一次混淆过程中各变换共享的状态
+type Context struct {
+	Program *ast.Program
+	Config  *Config
+	Source  string
+	// Rand 本次混淆专用的随机数生成器，由 Config.Seed 初始化
+	Rand *rand.Rand
+	// RenameMap 标识符混淆记录的改名映射，随结果一起返回
+	RenameMap []RenameEntry
+	// KeptNames 因用户保留而没有改名的声明
+	KeptNames []string
+	// PropertyMap 属性名改名的映射，随结果一起返回
+	PropertyMap map[string]string
+
+	reserved  *reservedNames
+	globals   map[string]bool
+	fileSet   *file.FileSet
+	names     map[string]bool   // 已使用的名称，由 newName 维护
+	strings   *stringRuntime    // 字符串变换共用的解码函数，由 stringRuntime 创建
+	templates *loweredTemplates // 模板字符串降级的结果，用于把位置换算回原始源码
+
+	selfDefending *selfDefendingCheck // 自我保护检查，由 selfDefendingCheck 创建
+}
+
+// IsReserved 判断名称是否必须保持原样：JavaScript 保留字或用户指定的保留名称
+//
+// 所有会改名的变换都应当跳过这些名称。
+func (ctx *Context) IsReserved(name string) bool {
+	return reservedIdentifiers[name] || ctx.reserved.match(name)
+}
+
+// IsGlobal 判断名称是否是目标环境（Config.Target）提供的内置全局名
+//
+// 改名和改写全局访问的变换都不能改动这些名称。
+func (ctx *Context) IsGlobal(name string) bool {
+	return ctx.globals[name]
+}
+
+// 内置变换的通用实现
+type builtinTransform struct {
+	name        string
+	description string
+	options     []Option
+	enabled     func(cfg *Config) bool
+	apply       func(ctx *Context) error
+}
+
+func (t *builtinTransform) Name() string             { return t.name }
+func (t *builtinTransform) Description() string      { return t.description }
+func (t *builtinTransform) Options() []Option        { return t.options }
+func (t *builtinTransform) Enabled(cfg *Config) bool { return t.enabled(cfg) }
+func (t *builtinTransform) Apply(ctx *Context) error { return t.apply(ctx) }
+
+// Registry 变换注册表，决定执行哪些变换以及执行顺序
+type Registry struct {
+	transforms []Transform
+	disabled   map[string]bool
+}
+
+// NewRegistry 创建空的注册表
+func NewRegistry() *Registry {
+	return &Registry{disabled: make(map[string]bool)}
+}
+
+// DefaultRegistry 创建包含全部内置变换的注册表
+func DefaultRegistry() *Registry {
+	r := NewRegistry()
+	r.transforms = builtinTransforms()
+	return r
+}
+
+// 内置变换，按默认执行顺序排列
+func builtinTransforms() []Transform {
+	return []Transform{
+		newIdentifierTransform(),
+		newPropertiesTransform(),
+		newSelfDefendingTransform(),
+		newMembersTransform(),
+		newObjectKeysTransform(),
+		newDecomposeTransform(),
+		newDeadCodeTransform(),
+		newSplitStringsTransform(),
+		newStringArrayTransform(),
+		newStringTransform(),
+		newControlFlowTransform(),
+		newLiteralsTransform(),
+		newNumbersTransform(),
+	}
+}
+
+// Transforms 按执行顺序返回已注册的变换
+func (r *Registry) Transforms() []Transform {
+	return append([]Transform(nil), r.transforms...)
+}
+
+// Lookup 按名称查找变换
+func (r *Registry) Lookup(name string) Transform {
+	if i := r.index(name); i >= 0 {
+		return r.transforms[i]
+	}
+	return nil
+}
+
+// Register 在末尾追加变换
+func (r *Registry) Register(t Transform) error {
+	return r.insert(len(r.transforms), t)
+}
+
+// InsertBefore 在指定变换之前插入
+func (r *Registry) InsertBefore(name string, t Transform) error {
+	i := r.index(name)
+	if i < 0 {
+		return errors.New("未知的变换: " + name)
+	}
+	return r.insert(i, t)
+}
+
+// InsertAfter 在指定变换之后插入
+func (r *Registry) InsertAfter(name string, t Transform) error {
+	i := r.index(name)
+	if i < 0 {
+		return errors.New("未知的变换: " + name)
+	}
+	return r.insert(i+1, t)
+}
+
+// Remove 移除变换，同时清除其禁用状态，之后注册的同名变换照常执行
+func (r *Registry) Remove(name string) {
+	if i := r.index(name); i >= 0 {
+		r.transforms = append(r.transforms[:i], r.transforms[i+1:]...)
+	}
+	delete(r.disabled, name)
+}
+
+// Reorder 按给定名称重新排列变换，未列出的变换保持原有相对顺序排在后面
+func (r *Registry) Reorder(names ...string) error {
+	ordered := make([]Transform, 0, len(r.transforms))
+	seen := make(map[string]bool)
+	for _, name := range names {
+		t := r.Lookup(name)
+		if t == nil {
+			return errors.New("未知的变换: " + name)
+		}
+		if seen[name] {
+			return errors.New("重复的变换: " + name)
+		}
+		seen[name] = true
+		ordered = append(ordered, t)
+	}
+	for _, t := range r.transforms {
+		if !seen[t.Name()] {
+			ordered = append(ordered, t)
+		}
+	}
+	r.transforms = ordered
+	return nil
+}
+
+// SetEnabled 单独启用或禁用变换；禁用的变换即使配置打开也不会执行
+func (r *Registry) SetEnabled(name string, enabled bool) error {
+	if r.index(name) < 0 {
+		return errors.New("未知的变换: " + name)
+	}
+	if enabled {
+		delete(r.disabled, name)
+	} else {
+		r.disabled[name] = true
+	}
+	return nil
+}
+
+// 依次执行启用的变换
+func (r *Registry) apply(ctx *Context) error {
+	for _, t := range r.transforms {
+		if r.disabled[t.Name()] || !t.Enabled(ctx.Config) {
+			continue
+		}
+		if err := t.Apply(ctx); err != nil {
+			return errors.New(t.Name() + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+func (r *Registry) index(name string) int {
+	for i, t := range r.transforms {
+		if t.Name() == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *Registry) insert(i int, t Transform) error {
+	if r.index(t.Name()) >= 0 {
+		return errors.New("变换已存在: " + t.Name())
+	}
+	r.transforms = append(r.transforms, nil)
+	copy(r.transforms[i+1:], r.transforms[i:])
+	r.transforms[i] = t
+	return nil
+}
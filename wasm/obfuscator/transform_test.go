@@ -0,0 +1,56 @@
+package obfuscator
+
+import (
+	"strings"
+	"testing"
+)
+
+// 总是启用的自定义变换
+func markerTransform(name string, apply func(ctx *Context) error) Transform {
+	return &builtinTransform{
+		name:        name,
+		description: "测试用变换",
+		enabled:     func(cfg *Config) bool { return true },
+		apply:       apply,
+	}
+}
+
+// 变换中的 panic 转为错误，错误信息包含 panic 的值
+func TestRegistryRecoversPanic(t *testing.T) {
+	registry := NewRegistry()
+	if err := registry.Register(markerTransform("broken", func(ctx *Context) error {
+		panic("索引越界 42")
+	})); err != nil {
+		t.Fatal(err)
+	}
+	_, err := registry.Obfuscate("var a = 1;", Config{Seed: 1})
+	if err == nil || !strings.Contains(err.Error(), "内部错误") || !strings.Contains(err.Error(), "索引越界 42") {
+		t.Errorf("错误为 %v，期望包含 panic 的值", err)
+	}
+}
+
+// 移除变换时清除禁用状态，之后注册的同名变换照常执行
+func TestRegistryRemoveClearsDisabled(t *testing.T) {
+	registry := NewRegistry()
+	if err := registry.Register(markerTransform("marker", func(ctx *Context) error { return nil })); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.SetEnabled("marker", false); err != nil {
+		t.Fatal(err)
+	}
+	registry.Remove("marker")
+
+	applied := false
+	if err := registry.Register(markerTransform("marker", func(ctx *Context) error {
+		applied = true
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registry.Obfuscate("var a = 1;", Config{Seed: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if !applied {
+		t.Error("重新注册的同名变换仍然被禁用")
+	}
+}
@@ -0,0 +1,97 @@
+package obfuscator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/robertkrimen/otto/file"
+	"github.com/robertkrimen/otto/parser"
+)
+
+// Validate 对代码做语法检查，返回是否通过以及错误列表
+//
+// 检查基于与混淆流程相同的解析器，因此能通过检查的代码一定可以被混淆。
+func Validate(code string) (bool, []string) {
+	var errors []string
+
+	// 检查代码是否为空
+	if len(strings.TrimSpace(code)) == 0 {
+		errors = append(errors, "代码不能为空")
+		return false, errors
+	}
+
+	// 语法解析，收集全部错误；模板字符串与混淆时一样先降级
+	templates := lowerTemplates(code)
+	_, err := parser.ParseFile(nil, "", templates.code, parser.IgnoreRegExpErrors)
+	if err != nil {
+		errors = append(errors, parseErrorMessages(templates, err)...)
+	}
+
+	return len(errors) == 0, errors
+}
+
+// 解析错误的说明
+//
+// 解析的是模板字符串降级后的代码，错误位置换算回原始源码。
+// 解析器只支持 ES5，遇到无法降级的模板字符串或 ES2020 运算符只会报 "Unexpected token ILLEGAL"，
+// 这里换成明确的提示。同一位置的重复错误只保留一条。
+func parseErrorMessages(templates *loweredTemplates, err error) []string {
+	list, ok := err.(*parser.ErrorList)
+	if !ok {
+		return []string{err.Error()}
+	}
+	code := templates.original
+	var messages []string
+	seen := make(map[string]bool)
+	for _, e := range *list {
+		original := *e
+		if offset := lineOffset(templates.code, e.Position.Line, e.Position.Column); offset >= 0 {
+			if position := templates.position(file.Idx(offset + 1)); position != nil {
+				original.Position.Line, original.Position.Column = position.Line, position.Column
+			}
+		}
+		message := original.Error()
+		line, column := original.Position.Line, original.Position.Column
+		switch {
+		case characterAt(code, line, column) == '`':
+			message = fmt.Sprintf("Line %d:%d 模板字符串无法转换为 ES5（没有结束或包含无效的转义）", line, column)
+		case characterAt(code, line, column-1) == '?' && characterAt(code, line, column) == '.':
+			message = fmt.Sprintf("Line %d:%d 不支持可选链 ?.（ES2020 语法），请先转译为 ES5", line, column-1)
+		case characterAt(code, line, column-1) == '?' && characterAt(code, line, column) == '?':
+			message = fmt.Sprintf("Line %d:%d 不支持空值合并 ??（ES2020 语法），请先转译为 ES5", line, column-1)
+		}
+		if !seen[message] {
+			seen[message] = true
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// 按行号和列号取出字节，超出范围时返回 0
+//
+// 行号和列号都从 1 开始；与解析器报告的位置一致，列是行内的字节偏移，
+// 行中有中文等多字节字符时不能按字符计数。
+func characterAt(code string, line, column int) byte {
+	offset := lineOffset(code, line, column)
+	if offset < 0 || offset >= len(code) {
+		return 0
+	}
+	return code[offset]
+}
+
+// 行号和列号对应的字节偏移，超出范围时返回 -1
+func lineOffset(code string, line, column int) int {
+	start := 0
+	for i := 1; i < line; i++ {
+		n := strings.IndexByte(code[start:], '\n')
+		if n < 0 {
+			return -1
+		}
+		start += n + 1
+	}
+	if column < 1 || start+column-1 > len(code) {
+		return -1
+	}
+	return start + column - 1
+}
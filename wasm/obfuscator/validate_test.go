@@ -0,0 +1,37 @@
+package obfuscator
+
+import (
+	"strings"
+	"testing"
+)
+
+// 不支持或无法降级的语法报告明确的提示，行中有多字节字符时位置仍然正确
+func TestValidateUnsupportedSyntax(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"var t = `x;", "Line 1:9 模板字符串无法转换为 ES5"},
+		{"var s = \"中文\"; var t = `\\u{g}`;", "Line 1:27 模板字符串无法转换为 ES5"},
+		{"var s = \"中文\"; var t = a?.b;", "Line 1:28 不支持可选链 ?."},
+		{"var s = \"中文\"; var t = a ?? b;", "Line 1:29 不支持空值合并 ??"},
+	}
+	for _, tc := range cases {
+		ok, errs := Validate(tc.src)
+		if ok || len(errs) == 0 || !strings.HasPrefix(errs[0], tc.want) {
+			t.Errorf("Validate(%q) = %v, want %q", tc.src, errs, tc.want)
+		}
+	}
+}
+
+// 打开属性访问改写时，可选链仍然在解析阶段报告同样的提示
+func TestOptionalChainingWithMembers(t *testing.T) {
+	src := "var o = { a: { b: 1 } };\nvar t = o.a?.b;"
+	_, err := Obfuscate(src, Config{TransformMemberExpressions: true})
+	if err == nil || !strings.Contains(err.Error(), "Line 2:12 不支持可选链 ?.（ES2020 语法），请先转译为 ES5") {
+		t.Errorf("Obfuscate(%q) 的错误为 %v", src, err)
+	}
+	if ok, errs := Validate(src); ok || len(errs) == 0 || !strings.Contains(err.Error(), errs[0]) {
+		t.Errorf("Validate(%q) = %v，与 Obfuscate 的错误 %v 不一致", src, errs, err)
+	}
+}
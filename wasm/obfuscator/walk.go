@@ -0,0 +1,165 @@
+package obfuscator
+
+import (
+	"github.com/robertkrimen/otto/ast"
+)
+
+// AST 遍历器
+//
+// enter 在访问子节点之前调用，返回 false 时跳过该节点的子节点；
+// leave 在子节点处理完之后调用，返回值会替换原节点。
+// 绑定位置的标识符（声明名、参数名、属性名、标签）不会作为表达式被访问。
+type astWalker struct {
+	enter func(node ast.Node) bool
+	leave func(node ast.Node) ast.Node
+}
+
+func (w *astWalker) walk(node ast.Node) ast.Node {
+	if w.enter != nil && !w.enter(node) {
+		return node
+	}
+
+	switch n := node.(type) {
+	case *ast.Program:
+		n.Body = w.statements(n.Body)
+
+	// 表达式
+	case *ast.ArrayLiteral:
+		for i, item := range n.Value {
+			n.Value[i] = w.expression(item)
+		}
+	case *ast.AssignExpression:
+		n.Left = w.expression(n.Left)
+		n.Right = w.expression(n.Right)
+	case *ast.BinaryExpression:
+		n.Left = w.expression(n.Left)
+		n.Right = w.expression(n.Right)
+	case *ast.BracketExpression:
+		n.Left = w.expression(n.Left)
+		n.Member = w.expression(n.Member)
+	case *ast.CallExpression:
+		n.Callee = w.expression(n.Callee)
+		for i, arg := range n.ArgumentList {
+			n.ArgumentList[i] = w.expression(arg)
+		}
+	case *ast.ConditionalExpression:
+		n.Test = w.expression(n.Test)
+		n.Consequent = w.expression(n.Consequent)
+		n.Alternate = w.expression(n.Alternate)
+	case *ast.DotExpression:
+		n.Left = w.expression(n.Left)
+	case *ast.FunctionLiteral:
+		n.Body = w.statement(n.Body)
+	case *ast.NewExpression:
+		n.Callee = w.expression(n.Callee)
+		for i, arg := range n.ArgumentList {
+			n.ArgumentList[i] = w.expression(arg)
+		}
+	case *ast.ObjectLiteral:
+		for i := range n.Value {
+			n.Value[i].Value = w.expression(n.Value[i].Value)
+		}
+	case *ast.SequenceExpression:
+		for i, item := range n.Sequence {
+			n.Sequence[i] = w.expression(item)
+		}
+	case *ast.UnaryExpression:
+		n.Operand = w.expression(n.Operand)
+	case *ast.VariableExpression:
+		n.Initializer = w.expression(n.Initializer)
+
+	// 语句
+	case *ast.BlockStatement:
+		n.List = w.statements(n.List)
+	case *ast.CaseStatement:
+		n.Test = w.expression(n.Test)
+		n.Consequent = w.statements(n.Consequent)
+	case *ast.CatchStatement:
+		n.Body = w.statement(n.Body)
+	case *ast.DoWhileStatement:
+		n.Body = w.statement(n.Body)
+		n.Test = w.expression(n.Test)
+	case *ast.ExpressionStatement:
+		n.Expression = w.expression(n.Expression)
+	case *ast.ForInStatement:
+		n.Into = w.expression(n.Into)
+		n.Source = w.expression(n.Source)
+		n.Body = w.statement(n.Body)
+	case *ast.ForStatement:
+		n.Initializer = w.expression(n.Initializer)
+		n.Test = w.expression(n.Test)
+		n.Update = w.expression(n.Update)
+		n.Body = w.statement(n.Body)
+	case *ast.FunctionStatement:
+		if fn, ok := w.walk(n.Function).(*ast.FunctionLiteral); ok {
+			n.Function = fn
+		}
+	case *ast.IfStatement:
+		n.Test = w.expression(n.Test)
+		n.Consequent = w.statement(n.Consequent)
+		n.Alternate = w.statement(n.Alternate)
+	case *ast.LabelledStatement:
+		n.Statement = w.statement(n.Statement)
+	case *ast.ReturnStatement:
+		n.Argument = w.expression(n.Argument)
+	case *ast.SwitchStatement:
+		n.Discriminant = w.expression(n.Discriminant)
+		for i, c := range n.Body {
+			if replaced, ok := w.walk(c).(*ast.CaseStatement); ok {
+				n.Body[i] = replaced
+			}
+		}
+	case *ast.ThrowStatement:
+		n.Argument = w.expression(n.Argument)
+	case *ast.TryStatement:
+		n.Body = w.statement(n.Body)
+		if n.Catch != nil {
+			if replaced, ok := w.walk(n.Catch).(*ast.CatchStatement); ok {
+				n.Catch = replaced
+			}
+		}
+		n.Finally = w.statement(n.Finally)
+	case *ast.VariableStatement:
+		for i, item := range n.List {
+			n.List[i] = w.expression(item)
+		}
+	case *ast.WhileStatement:
+		n.Test = w.expression(n.Test)
+		n.Body = w.statement(n.Body)
+	case *ast.WithStatement:
+		n.Object = w.expression(n.Object)
+		n.Body = w.statement(n.Body)
+	}
+
+	if w.leave != nil {
+		return w.leave(node)
+	}
+	return node
+}
+
+func (w *astWalker) expression(expr ast.Expression) ast.Expression {
+	if expr == nil {
+		return nil
+	}
+	if replaced, ok := w.walk(expr).(ast.Expression); ok {
+		return replaced
+	}
+	return expr
+}
+
+func (w *astWalker) statement(stmt ast.Statement) ast.Statement {
+	if stmt == nil {
+		return nil
+	}
+	if replaced, ok := w.walk(stmt).(ast.Statement); ok {
+		return replaced
+	}
+	return stmt
+}
+
+func (w *astWalker) statements(list []ast.Statement) []ast.Statement {
+	for i, stmt := range list {
+		list[i] = w.statement(stmt)
+	}
+	return list
+}